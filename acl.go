@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	aclFile           = flag.String("acl", "", "Path to the JSON access policy, when set callers only get the rights it grants")
+	aclReloadInterval = flag.Duration("acl_reload_interval", time.Second*30, "How often to check the access policy for changes")
+)
+
+var (
+	aclDenied = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_acl_denied",
+	}, []string{"caller", "right"})
+	aclReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_acl_reloads",
+	}, []string{"result"})
+)
+
+const (
+	rightRead   = "read"
+	rightWrite  = "write"
+	rightDelete = "delete"
+	rightList   = "list"
+)
+
+// aclPolicy grants rights on key prefixes. Prefixes match stored keys, so a
+// namespace is granted through its namespace prefix and counters through theirs.
+//
+//	{
+//	  "rules": [
+//	    {"callers": ["builder"], "prefix": "builds/", "rights": ["read", "write", "list"]},
+//	    {"callers": ["*"], "prefix": "public/", "rights": ["read"]}
+//	  ]
+//	}
+type aclPolicy struct {
+	Rules []*aclRule `json:"rules"`
+}
+
+type aclRule struct {
+	Callers []string `json:"callers"`
+	Prefix  string   `json:"prefix"`
+	Rights  []string `json:"rights"`
+}
+
+func parseACL(data []byte) (*aclPolicy, error) {
+	policy := &aclPolicy{}
+	if err := json.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("bad acl: %w", err)
+	}
+	for i, rule := range policy.Rules {
+		if len(rule.Callers) == 0 {
+			return nil, fmt.Errorf("rule %v names no callers", i)
+		}
+		for _, right := range rule.Rights {
+			if right != rightRead && right != rightWrite && right != rightDelete && right != rightList {
+				return nil, fmt.Errorf("rule %v has unknown right %q", i, right)
+			}
+		}
+	}
+	return policy, nil
+}
+
+func (r *aclRule) grants(caller, right string) bool {
+	found := false
+	for _, c := range r.Callers {
+		if c == caller || c == "*" {
+			found = true
+		}
+	}
+	if !found {
+		return false
+	}
+	for _, g := range r.Rights {
+		if g == right {
+			return true
+		}
+	}
+	return false
+}
+
+// allows reports whether caller has right over everything under key
+func (p *aclPolicy) allows(caller, right, key string) bool {
+	for _, rule := range p.Rules {
+		if strings.HasPrefix(key, rule.Prefix) && rule.grants(caller, right) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsSome reports whether caller has right over some of the keys under prefix
+func (p *aclPolicy) allowsSome(caller, right, prefix string) bool {
+	for _, rule := range p.Rules {
+		if strings.HasPrefix(rule.Prefix, prefix) && rule.grants(caller, right) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) policy() *aclPolicy {
+	s.aclLock.RLock()
+	defer s.aclLock.RUnlock()
+	return s.acl
+}
+
+// deny refuses the caller right on key, recording the refusal in the audit log as a deny_<right> op
+func (s *Server) deny(ctx context.Context, right, key string) error {
+	caller := callerFrom(ctx)
+	aclDenied.With(prometheus.Labels{"caller": caller, "right": right}).Inc()
+	log.Printf("Denied %v %v on %q", caller, right, key)
+	err := status.Errorf(codes.PermissionDenied, "%v may not %v %v", caller, right, key)
+	s.finishAudit(s.newAudit(ctx, "deny_"+right, key, nil), err)
+	return err
+}
+
+// authorize checks the caller may use right on key, calls made by pstore itself carry no caller and are always allowed
+func (s *Server) authorize(ctx context.Context, right, key string) error {
+	policy := s.policy()
+	caller := callerFrom(ctx)
+	if policy == nil || caller == "" || policy.allows(caller, right, key) {
+		return nil
+	}
+	return s.deny(ctx, right, key)
+}
+
+// readable reports whether the caller may read key, without counting a denial
+func (s *Server) readable(ctx context.Context, key string) bool {
+	policy := s.policy()
+	caller := callerFrom(ctx)
+	return policy == nil || caller == "" || policy.allows(caller, rightRead, key)
+}
+
+// authorizeList checks the caller may list under prefix, returning a filter
+// to apply to the keys when only part of the prefix is visible to them
+func (s *Server) authorizeList(ctx context.Context, prefix string) (func([]string) []string, error) {
+	policy := s.policy()
+	caller := callerFrom(ctx)
+	if policy == nil || caller == "" || policy.allows(caller, rightList, prefix) {
+		return func(keys []string) []string { return keys }, nil
+	}
+	if !policy.allowsSome(caller, rightList, prefix) {
+		return nil, s.deny(ctx, rightList, prefix)
+	}
+	return func(keys []string) []string {
+		var result []string
+		for _, key := range keys {
+			if policy.allows(caller, rightList, key) {
+				result = append(result, key)
+			}
+		}
+		return result
+	}, nil
+}
+
+func (s *Server) loadACL(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	policy, err := parseACL(data)
+	if err != nil {
+		return err
+	}
+	s.aclLock.Lock()
+	s.acl = policy
+	s.aclLock.Unlock()
+	return nil
+}
+
+// runACLReload picks up changes to the policy file, a bad file leaves the current policy in place
+func (s *Server) runACLReload(path string) {
+	last := time.Time{}
+	if info, err := os.Stat(path); err == nil {
+		last = info.ModTime()
+	}
+	for range time.Tick(*aclReloadInterval) {
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().Equal(last) {
+			continue
+		}
+		last = info.ModTime()
+		if err := s.loadACL(path); err != nil {
+			log.Printf("Unable to reload acl: %v", err)
+			aclReloads.With(prometheus.Labels{"result": "error"}).Inc()
+			continue
+		}
+		log.Printf("Reloaded acl from %v", path)
+		aclReloads.With(prometheus.Labels{"result": "ok"}).Inc()
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const testPolicy = `{"rules": [
+	{"callers": ["builder"], "prefix": "builds/", "rights": ["read", "write", "list"]},
+	{"callers": ["*"], "prefix": "public/", "rights": ["read", "list"]}
+]}`
+
+func TestACL(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+	dir := t.TempDir()
+	path := filepath.Join(dir, "acl.json")
+	os.WriteFile(path, []byte(testPolicy), 0600)
+	if err := s.loadACL(path); err != nil {
+		t.Fatalf("Unable to load acl: %v", err)
+	}
+
+	// pstore itself is not bound by the policy
+	for _, key := range []string{"builds/one", "public/one", "private/one"} {
+		if _, err := s.Write(context.Background(), &pb.WriteRequest{Key: key, Value: &anypb.Any{Value: []byte(key)}}); err != nil {
+			t.Fatalf("Internal write failed: %v", err)
+		}
+	}
+
+	builder := withCaller(context.Background(), "builder")
+	other := withCaller(context.Background(), "other")
+
+	if _, err := s.Write(builder, &pb.WriteRequest{Key: "builds/two", Value: &anypb.Any{}}); err != nil {
+		t.Errorf("Builder should be able to write builds: %v", err)
+	}
+	if _, err := s.Delete(builder, &pb.DeleteRequest{Key: "builds/two"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Builder should not be able to delete: %v", err)
+	}
+	if _, err := s.Read(other, &pb.ReadRequest{Key: "builds/one"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Other should not read builds: %v", err)
+	}
+	if _, err := s.Read(other, &pb.ReadRequest{Key: "public/one"}); err != nil {
+		t.Errorf("Anyone should read public: %v", err)
+	}
+	if _, err := s.Count(other, &pb.CountRequest{Counter: "hits"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Other should not count: %v", err)
+	}
+
+	// Listing everything only shows the parts the caller may list
+	keys, err := s.GetKeys(other, &pb.GetKeysRequest{AllKeys: true})
+	if err != nil || strings.Join(keys.GetKeys(), ",") != "public/one" {
+		t.Errorf("Bad filtered listing: %v, %v", keys, err)
+	}
+	if _, err := s.GetKeys(other, &pb.GetKeysRequest{Prefix: "private/"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Listing private should fail: %v", err)
+	}
+
+	// A bad policy is refused and the old one stays
+	os.WriteFile(path, []byte(`{"rules": [{"callers": ["x"], "rights": ["fly"]}]}`), 0600)
+	if err := s.loadACL(path); err == nil {
+		t.Errorf("Bad policy should not load")
+	}
+	if _, err := s.Read(other, &pb.ReadRequest{Key: "public/one"}); err != nil {
+		t.Errorf("Old policy should still apply: %v", err)
+	}
+}
+
+func TestDenialsAreAudited(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+	path := filepath.Join(t.TempDir(), "acl.json")
+	os.WriteFile(path, []byte(testPolicy), 0600)
+	if err := s.loadACL(path); err != nil {
+		t.Fatalf("Unable to load acl: %v", err)
+	}
+	audit, err := openAuditLog(filepath.Join(t.TempDir(), "audit.log"), 1024*1024, 2)
+	if err != nil {
+		t.Fatalf("Unable to open audit log: %v", err)
+	}
+	s.audit = audit
+
+	other := withCaller(context.Background(), "other")
+	if _, err := s.Read(other, &pb.ReadRequest{Key: "builds/one"}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("Other should not read builds: %v", err)
+	}
+	entries := waitForAudit(t, s, "builds/one", 1)
+	if entries[0].GetOp() != "deny_read" || entries[0].GetCaller() != "other" || entries[0].GetCode() != "PermissionDenied" {
+		t.Errorf("Bad denial entry: %v", entries[0])
+	}
+}
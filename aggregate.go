@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+var (
+	aggregateValues = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_aggregate_values",
+	}, []string{"result"})
+)
+
+// numericValues returns the values at path as float64s, failing for non numeric fields
+func numericValues(msg protoreflect.Message, path string) ([]float64, error) {
+	fd, values, err := lookupField(msg, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []float64
+	for _, v := range values {
+		switch fd.Kind() {
+		case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+			protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+			result = append(result, float64(v.Int()))
+		case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+			result = append(result, float64(v.Uint()))
+		case protoreflect.FloatKind, protoreflect.DoubleKind:
+			result = append(result, v.Float())
+		case protoreflect.EnumKind:
+			result = append(result, float64(v.Enum()))
+		default:
+			return nil, fmt.Errorf("%v is not numeric", fd.FullName())
+		}
+	}
+	return result, nil
+}
+
+// aggregateGroup accumulates the results for one group
+type aggregateGroup struct {
+	count   int64
+	results []float64
+	seen    []bool
+}
+
+func newAggregateGroup(aggs []*pb.Aggregation) *aggregateGroup {
+	g := &aggregateGroup{results: make([]float64, len(aggs)), seen: make([]bool, len(aggs))}
+	for i, agg := range aggs {
+		if agg.GetOp() == pb.Aggregation_MIN || agg.GetOp() == pb.Aggregation_MAX {
+			g.results[i] = math.NaN()
+		}
+	}
+	return g
+}
+
+func (g *aggregateGroup) add(aggs []*pb.Aggregation, values [][]float64) {
+	g.count++
+	for i, agg := range aggs {
+		switch agg.GetOp() {
+		case pb.Aggregation_COUNT:
+			g.results[i]++
+		case pb.Aggregation_SUM:
+			for _, v := range values[i] {
+				g.results[i] += v
+			}
+		case pb.Aggregation_MIN:
+			for _, v := range values[i] {
+				if !g.seen[i] || v < g.results[i] {
+					g.results[i] = v
+				}
+				g.seen[i] = true
+			}
+		case pb.Aggregation_MAX:
+			for _, v := range values[i] {
+				if !g.seen[i] || v > g.results[i] {
+					g.results[i] = v
+				}
+				g.seen[i] = true
+			}
+		}
+	}
+}
+
+func (s *Server) Aggregate(ctx context.Context, req *pb.AggregateRequest) (*pb.AggregateResponse, error) {
+	needsMessage := req.GetGroupBy() != ""
+	for _, agg := range req.GetAggregations() {
+		if _, ok := pb.Aggregation_Op_name[int32(agg.GetOp())]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown aggregation %v", agg.GetOp())
+		}
+		if agg.GetOp() != pb.Aggregation_COUNT {
+			if agg.GetField() == "" {
+				return nil, status.Errorf(codes.InvalidArgument, "%v needs a field", agg.GetOp())
+			}
+			needsMessage = true
+		}
+	}
+
+	groups := make(map[string]*aggregateGroup)
+	err := s.scanValues(ctx, req.GetPrefix(), req.GetFilter(), func(key string, value *anypb.Any, msg proto.Message) error {
+		if msg == nil && needsMessage {
+			aggregateValues.With(prometheus.Labels{"result": "unknown_type"}).Inc()
+			return nil
+		}
+
+		names := []string{""}
+		if req.GetGroupBy() != "" {
+			var err error
+			names, err = fieldValues(msg.ProtoReflect(), req.GetGroupBy())
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "cannot group %v: %v", key, err)
+			}
+		}
+
+		values := make([][]float64, len(req.GetAggregations()))
+		for i, agg := range req.GetAggregations() {
+			if agg.GetOp() == pb.Aggregation_COUNT {
+				continue
+			}
+			var err error
+			values[i], err = numericValues(msg.ProtoReflect(), agg.GetField())
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "cannot aggregate %v: %v", key, err)
+			}
+		}
+
+		// A value with a repeated group field counts towards each of its groups
+		for _, name := range names {
+			g, ok := groups[name]
+			if !ok {
+				g = newAggregateGroup(req.GetAggregations())
+				groups[name] = g
+			}
+			g.add(req.GetAggregations(), values)
+		}
+		aggregateValues.With(prometheus.Labels{"result": "counted"}).Inc()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.AggregateResponse{}
+	for name, g := range groups {
+		resp.Groups = append(resp.Groups, &pb.AggregateGroup{Group: name, Count: g.count, Results: g.results})
+	}
+	sort.Slice(resp.Groups, func(i, j int) bool {
+		return resp.Groups[i].GetGroup() < resp.Groups[j].GetGroup()
+	})
+	return resp, nil
+}
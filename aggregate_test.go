@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestAggregate(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+	s.registerDescriptors(context.Background(), testDescriptors())
+
+	s.Write(context.Background(), &pb.WriteRequest{Key: "things/a", Value: thing("small", 2)})
+	s.Write(context.Background(), &pb.WriteRequest{Key: "things/b", Value: thing("large", 20)})
+	s.Write(context.Background(), &pb.WriteRequest{Key: "things/c", Value: thing("large", 30)})
+	s.Write(context.Background(), &pb.WriteRequest{Key: "things/raw", Value: &anypb.Any{Value: []byte{1}}})
+
+	aggs := []*pb.Aggregation{
+		{Op: pb.Aggregation_SUM, Field: "size"},
+		{Op: pb.Aggregation_MIN, Field: "size"},
+		{Op: pb.Aggregation_MAX, Field: "size"},
+	}
+	resp, err := s.Aggregate(context.Background(), &pb.AggregateRequest{Prefix: "things/", GroupBy: "name", Aggregations: aggs})
+	if err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+	if len(resp.GetGroups()) != 2 {
+		t.Fatalf("Wrong groups: %v", resp)
+	}
+	large := resp.GetGroups()[0]
+	if large.GetGroup() != "large" || large.GetCount() != 2 || large.GetResults()[0] != 50 || large.GetResults()[1] != 20 || large.GetResults()[2] != 30 {
+		t.Errorf("Bad large group: %v", large)
+	}
+	if small := resp.GetGroups()[1]; small.GetGroup() != "small" || small.GetResults()[0] != 2 {
+		t.Errorf("Bad small group: %v", small)
+	}
+
+	// A plain count covers values of unknown types too
+	resp, err = s.Aggregate(context.Background(), &pb.AggregateRequest{Prefix: "things/"})
+	if err != nil || len(resp.GetGroups()) != 1 || resp.GetGroups()[0].GetCount() != 4 {
+		t.Errorf("Bad count: %v, %v", resp, err)
+	}
+
+	resp, err = s.Aggregate(context.Background(), &pb.AggregateRequest{Prefix: "things/", Filter: `msg.size > 100`, Aggregations: aggs})
+	if err != nil || len(resp.GetGroups()) != 0 {
+		t.Errorf("Filtered everything should give no groups: %v, %v", resp, err)
+	}
+
+	if _, err := s.Aggregate(context.Background(), &pb.AggregateRequest{Prefix: "things/", Aggregations: []*pb.Aggregation{{Op: pb.Aggregation_SUM, Field: "name"}}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Summing a string should fail: %v", err)
+	}
+	if _, err := s.Aggregate(context.Background(), &pb.AggregateRequest{Prefix: "things/", Aggregations: []*pb.Aggregation{{Op: pb.Aggregation_MAX}}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Max without a field should fail: %v", err)
+	}
+}
+
+func TestAggregateEmptyGroup(t *testing.T) {
+	g := newAggregateGroup([]*pb.Aggregation{{Op: pb.Aggregation_MIN, Field: "size"}})
+	g.add([]*pb.Aggregation{{Op: pb.Aggregation_MIN, Field: "size"}}, [][]float64{nil})
+	if g.count != 1 || !math.IsNaN(g.results[0]) {
+		t.Errorf("Min over no values should be NaN: %v", g.results)
+	}
+}
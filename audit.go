@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// ACL rules grant access to the audit log through this prefix, nothing is stored under it
+const auditPrefix = reservedPrefix + "audit/"
+
+var (
+	auditFile     = flag.String("audit_log", "", "Path of the mutation audit log, empty disables auditing")
+	auditMaxBytes = flag.Int64("audit_max_bytes", 100*1024*1024, "Size at which the audit log is rotated")
+	auditFiles    = flag.Int("audit_files", 10, "Number of rotated audit logs to keep")
+)
+
+var (
+	auditCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_audit_entries",
+	}, []string{"op", "result"})
+)
+
+// auditLog appends entries as JSON lines to a file, rotating it to path.1, path.2 and so on
+type auditLog struct {
+	lock     sync.Mutex
+	path     string
+	maxBytes int64
+	keep     int
+	file     *os.File
+	size     int64
+}
+
+func openAuditLog(path string, maxBytes int64, keep int) (*auditLog, error) {
+	a := &auditLog{path: path, maxBytes: maxBytes, keep: keep}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *auditLog) open() error {
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	a.file = file
+	a.size = info.Size()
+	return nil
+}
+
+func (a *auditLog) rotated(i int) string {
+	return fmt.Sprintf("%v.%v", a.path, i)
+}
+
+func (a *auditLog) rotate() error {
+	if err := a.file.Close(); err != nil {
+		return err
+	}
+	os.Remove(a.rotated(a.keep))
+	for i := a.keep - 1; i >= 1; i-- {
+		os.Rename(a.rotated(i), a.rotated(i+1))
+	}
+	if a.keep > 0 {
+		if err := os.Rename(a.path, a.rotated(1)); err != nil {
+			return err
+		}
+	} else {
+		os.Remove(a.path)
+	}
+	return a.open()
+}
+
+func (a *auditLog) append(entry *pb.AuditEntry) error {
+	data, err := protojson.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.size > 0 && a.size+int64(len(data)) > a.maxBytes {
+		if err := a.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := a.file.Write(data)
+	a.size += int64(n)
+	return err
+}
+
+// query reads every entry matching req, oldest first. The files are opened under the
+// lock, so a rotation can't move them mid query, and read without it.
+func (a *auditLog) query(req *pb.QueryAuditRequest) ([]*pb.AuditEntry, error) {
+	paths := []string{a.path}
+	for i := 1; i <= a.keep; i++ {
+		paths = append(paths, a.rotated(i))
+	}
+
+	var readers []io.Reader
+	var files []*os.File
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+	a.lock.Lock()
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			a.lock.Unlock()
+			return nil, err
+		}
+		files = append(files, file)
+		readers = append(readers, file)
+	}
+	// The live file is only read as far as it has been written, never into an entry being appended
+	if len(files) > 0 && files[0].Name() == a.path {
+		readers[0] = io.LimitReader(files[0], a.size)
+	}
+	a.lock.Unlock()
+
+	var result []*pb.AuditEntry
+	for _, file := range readers {
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(nil, 1024*1024)
+		for scanner.Scan() {
+			entry := &pb.AuditEntry{}
+			if err := protojson.Unmarshal(scanner.Bytes(), entry); err != nil {
+				continue
+			}
+			if req.GetKey() != "" && entry.GetKey() != req.GetKey() {
+				continue
+			}
+			if req.GetStartTime() > 0 && entry.GetTimestamp() < req.GetStartTime()*int64(time.Second) {
+				continue
+			}
+			if req.GetEndTime() > 0 && entry.GetTimestamp() > req.GetEndTime()*int64(time.Second) {
+				continue
+			}
+			result = append(result, entry)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].GetTimestamp() < result[j].GetTimestamp()
+	})
+	if req.GetLimit() > 0 && len(result) > int(req.GetLimit()) {
+		result = result[len(result)-int(req.GetLimit()):]
+	}
+	return result, nil
+}
+
+// auditRecord collects the outcome of one mutation as each backend answers
+type auditRecord struct {
+	lock    sync.Mutex
+	entry   *pb.AuditEntry
+	pending sync.WaitGroup
+}
+
+// newAudit starts recording a mutation, it returns nil when auditing is off
+func (s *Server) newAudit(ctx context.Context, op, key string, value []byte) *auditRecord {
+	if s.audit == nil {
+		return nil
+	}
+	caller := callerFrom(ctx)
+	if caller == "" {
+		caller = "pstore"
+	}
+	entry := &pb.AuditEntry{Timestamp: time.Now().UnixNano(), Caller: caller, Op: op, Key: key, Size: int64(len(value))}
+	if value != nil {
+		sum := sha256.Sum256(value)
+		entry.Hash = hex.EncodeToString(sum[:])
+	}
+	return &auditRecord{entry: entry}
+}
+
+func (a *auditRecord) backend(name string, err error) {
+	if a == nil {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.entry.Backends = append(a.entry.Backends, &pb.BackendResult{Backend: name, Code: fmt.Sprintf("%v", status.Code(err))})
+}
+
+func (a *auditRecord) setDelta(delta int64) {
+	if a != nil {
+		a.entry.Delta = delta
+	}
+}
+
+// expect marks a backend whose answer will come later through answer
+func (a *auditRecord) expect() {
+	if a != nil {
+		a.pending.Add(1)
+	}
+}
+
+func (a *auditRecord) answer(name string, err error) {
+	if a != nil {
+		a.backend(name, err)
+		a.pending.Done()
+	}
+}
+
+// finishAudit writes the record once every backend has answered
+func (s *Server) finishAudit(a *auditRecord, err error) {
+	if a == nil {
+		return
+	}
+	a.entry.Code = fmt.Sprintf("%v", status.Code(err))
+	go func() {
+		a.pending.Wait()
+		a.lock.Lock()
+		defer a.lock.Unlock()
+		if aerr := s.audit.append(a.entry); aerr != nil {
+			log.Printf("Unable to audit %v of %v: %v", a.entry.GetOp(), a.entry.GetKey(), aerr)
+			auditCount.With(prometheus.Labels{"op": a.entry.GetOp(), "result": "error"}).Inc()
+			return
+		}
+		auditCount.With(prometheus.Labels{"op": a.entry.GetOp(), "result": "ok"}).Inc()
+	}()
+}
+
+func (s *Server) QueryAudit(ctx context.Context, req *pb.QueryAuditRequest) (*pb.QueryAuditResponse, error) {
+	if err := s.authorize(ctx, rightRead, auditPrefix); err != nil {
+		return nil, err
+	}
+	if s.audit == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "auditing is not enabled")
+	}
+
+	entries, err := s.audit.query(req)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to read audit log: %v", err)
+	}
+	return &pb.QueryAuditResponse{Entries: entries}, nil
+}
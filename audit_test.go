@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// waitForAudit polls until count entries for key have been written
+func waitForAudit(t *testing.T, s *Server, key string, count int) []*pb.AuditEntry {
+	for i := 0; i < 100; i++ {
+		resp, err := s.QueryAudit(context.Background(), &pb.QueryAuditRequest{Key: key})
+		if err != nil {
+			t.Fatalf("Bad query: %v", err)
+		}
+		if len(resp.GetEntries()) >= count {
+			return resp.GetEntries()
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	t.Fatalf("Audit entries for %v never arrived", key)
+	return nil
+}
+
+func TestAudit(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"), getTestBackend("secondary"))
+	if _, err := s.QueryAudit(context.Background(), &pb.QueryAuditRequest{}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Query without an audit log should fail: %v", err)
+	}
+
+	audit, err := openAuditLog(filepath.Join(t.TempDir(), "audit.log"), 1024*1024, 2)
+	if err != nil {
+		t.Fatalf("Unable to open audit log: %v", err)
+	}
+	s.audit = audit
+
+	ctx := withCaller(context.Background(), "builder")
+	s.Write(ctx, &pb.WriteRequest{Key: "things/one", Value: &anypb.Any{Value: []byte("hello")}})
+	s.Delete(ctx, &pb.DeleteRequest{Key: "things/one"})
+	s.Count(ctx, &pb.CountRequest{Counter: "hits", Delta: 3})
+
+	entries := waitForAudit(t, s, "things/one", 2)
+	write := entries[0]
+	if write.GetOp() != "write" || write.GetCaller() != "builder" || write.GetSize() != 5 || write.GetHash() == "" || write.GetCode() != "OK" || len(write.GetBackends()) != 2 {
+		t.Errorf("Bad write entry: %v", write)
+	}
+	if entries[1].GetOp() != "delete" || len(entries[1].GetBackends()) != 2 {
+		t.Errorf("Bad delete entry: %v", entries[1])
+	}
+
+	count := waitForAudit(t, s, counterKey("hits"), 1)
+	if count[0].GetOp() != "count" || count[0].GetDelta() != 3 {
+		t.Errorf("Bad count entry: %v", count[0])
+	}
+
+	resp, err := s.QueryAudit(context.Background(), &pb.QueryAuditRequest{EndTime: time.Now().Add(-time.Hour).Unix()})
+	if err != nil || len(resp.GetEntries()) != 0 {
+		t.Errorf("Time range should exclude everything: %v, %v", resp, err)
+	}
+}
+
+func TestAuditRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := openAuditLog(path, 200, 2)
+	if err != nil {
+		t.Fatalf("Unable to open audit log: %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		if err := audit.append(&pb.AuditEntry{Timestamp: int64(i), Op: "write", Key: "things/one"}); err != nil {
+			t.Fatalf("Bad append: %v", err)
+		}
+	}
+
+	if _, err := os.Stat(path + ".2"); err != nil {
+		t.Errorf("Log was not rotated: %v", err)
+	}
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Errorf("Too many logs kept")
+	}
+
+	entries, err := audit.query(&pb.QueryAuditRequest{Limit: 2})
+	if err != nil || len(entries) != 2 || entries[1].GetTimestamp() != 19 {
+		t.Errorf("Bad limited query: %v, %v", entries, err)
+	}
+}
+
+func TestAuditQueryDuringRotation(t *testing.T) {
+	audit, err := openAuditLog(filepath.Join(t.TempDir(), "audit.log"), 500, 3)
+	if err != nil {
+		t.Fatalf("Unable to open audit log: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 500; i++ {
+			audit.append(&pb.AuditEntry{Timestamp: int64(i), Op: "write", Key: "things/one"})
+		}
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		entries, err := audit.query(&pb.QueryAuditRequest{Key: "things/one"})
+		if err != nil {
+			t.Fatalf("Bad query: %v", err)
+		}
+		for i := 1; i < len(entries); i++ {
+			if entries[i].GetTimestamp() <= entries[i-1].GetTimestamp() {
+				t.Fatalf("Query saw a torn or repeated entry: %v", entries)
+			}
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	tlsCert     = flag.String("tls_cert", "", "Serving certificate, setting this enables TLS on the serving port")
+	tlsKey      = flag.String("tls_key", "", "Key for --tls_cert")
+	tlsClientCA = flag.String("tls_client_ca", "", "CA bundle used to verify client certificates")
+	tokenFile   = flag.String("tokens", "", "Path to a JSON map of caller name to bearer token")
+	requireAuth = flag.Bool("require_auth", false, "Reject callers that present neither a verified client certificate nor a known token")
+
+	backendCA   = flag.String("backend_tls_ca", "", "CA bundle for backend dials, setting this enables TLS to the backends")
+	backendCert = flag.String("backend_tls_cert", "", "Client certificate presented to the backends")
+	backendKey  = flag.String("backend_tls_key", "", "Key for --backend_tls_cert")
+)
+
+var (
+	callCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_calls",
+	}, []string{"caller", "method", "code"})
+)
+
+// Callers that did not authenticate, when that is allowed
+const anonymousCaller = "anonymous"
+
+type callerKey struct{}
+
+// callerFrom returns the authenticated caller, or "" for calls made by pstore itself
+func callerFrom(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+func withCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// loadTokens reads the token file, tokens are held by their hash
+func loadTokens(path string) (map[[32]byte]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseTokens(data)
+}
+
+func parseTokens(data []byte) (map[[32]byte]string, error) {
+	byName := make(map[string]string)
+	if err := json.Unmarshal(data, &byName); err != nil {
+		return nil, fmt.Errorf("bad tokens: %w", err)
+	}
+
+	tokens := make(map[[32]byte]string)
+	for name, token := range byName {
+		if name == "" || name == anonymousCaller || len(token) < 16 {
+			return nil, fmt.Errorf("bad token for %q, tokens must be at least 16 characters", name)
+		}
+		tokens[sha256.Sum256([]byte(token))] = name
+	}
+	return tokens, nil
+}
+
+// tokenCaller finds the caller named by a bearer token in the authorization values
+func (s *Server) tokenCaller(authorization []string) (string, error) {
+	for _, value := range authorization {
+		token, ok := strings.CutPrefix(value, "Bearer ")
+		if !ok {
+			continue
+		}
+		if name, ok := s.tokens[sha256.Sum256([]byte(token))]; ok {
+			return name, nil
+		}
+		return "", status.Errorf(codes.Unauthenticated, "unknown token")
+	}
+	return "", nil
+}
+
+// authenticate works out who is calling, from the client certificate or a bearer token
+func (s *Server) authenticate(ctx context.Context) (string, error) {
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 {
+			return certName(info.State.VerifiedChains[0][0]), nil
+		}
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	caller, err := s.tokenCaller(md.Get("authorization"))
+	if err != nil || caller != "" {
+		return caller, err
+	}
+
+	if s.requireAuth {
+		return "", status.Errorf(codes.Unauthenticated, "no client certificate or token")
+	}
+	return anonymousCaller, nil
+}
+
+// certName names a caller by the common name of its certificate, or failing that its first DNS name
+func certName(cert *x509.Certificate) string {
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	if len(cert.DNSNames) > 0 {
+		return cert.DNSNames[0]
+	}
+	return cert.Subject.String()
+}
+
+func (s *Server) unaryAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	caller, err := s.authenticate(ctx)
+	var resp interface{}
+	if err == nil {
+		resp, err = handler(withCaller(ctx, caller), req)
+	} else {
+		log.Printf("Rejected call to %v: %v", info.FullMethod, err)
+	}
+	callCount.With(prometheus.Labels{"caller": caller, "method": info.FullMethod, "code": fmt.Sprintf("%v", status.Code(err))}).Inc()
+	return resp, err
+}
+
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (a *authStream) Context() context.Context {
+	return a.ctx
+}
+
+func (s *Server) streamAuth(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	caller, err := s.authenticate(ss.Context())
+	if err == nil {
+		err = handler(srv, &authStream{ServerStream: ss, ctx: withCaller(ss.Context(), caller)})
+	} else {
+		log.Printf("Rejected call to %v: %v", info.FullMethod, err)
+	}
+	callCount.With(prometheus.Labels{"caller": caller, "method": info.FullMethod, "code": fmt.Sprintf("%v", status.Code(err))}).Inc()
+	return err
+}
+
+// httpAuth authenticates HTTP requests by bearer token and admits them as the gRPC
+// interceptors would, method names the RPC the handler stands in for
+func (s *Server) httpAuth(method string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller, err := s.tokenCaller(r.Header.Values("Authorization"))
+		if err == nil && caller == "" {
+			if s.requireAuth {
+				err = status.Errorf(codes.Unauthenticated, "no token")
+			}
+			caller = anonymousCaller
+		}
+		if err != nil {
+			writeHTTPError(w, r, err)
+			return
+		}
+		ctx := withCaller(r.Context(), caller)
+
+		if err := s.rateLimit(ctx, method); err != nil {
+			writeHTTPError(w, r, err)
+			return
+		}
+		low := r.Header.Get(priorityMetadata) == "low" || (method == "GetKeys" && r.URL.Query().Get("prefix") == "")
+		if err := s.shed(method, low); err != nil {
+			writeHTTPError(w, r, err)
+			return
+		}
+		handler(w, r.WithContext(ctx))
+	}
+}
+
+func loadPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates in %v", path)
+	}
+	return pool, nil
+}
+
+// serverCredentials builds the TLS config for the serving port. Client certificates are
+// verified when given but not demanded, so token callers can still connect.
+func serverCredentials(cert, key, clientCA string) (credentials.TransportCredentials, error) {
+	pair, err := tls.LoadX509KeyPair(cert, key)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{pair}, MinVersion: tls.VersionTLS12}
+	if clientCA != "" {
+		pool, err := loadPool(clientCA)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return credentials.NewTLS(config), nil
+}
+
+// backendCredentials builds the credentials used to dial the backends, plain text unless a CA is given
+func backendCredentials(ca, cert, key string) (credentials.TransportCredentials, error) {
+	if ca == "" {
+		return insecure.NewCredentials(), nil
+	}
+	pool, err := loadPool(ca)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	if cert != "" {
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{pair}
+	}
+	return credentials.NewTLS(config), nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"io"
+	"log"
+	"strings"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+// Size of the pieces an archive is streamed in
+const exportChunkSize = 1024 * 1024
+
+// Keys pstore derives from other keys, these are rebuilt rather than backed up
+var derivedPrefixes = []string{changelogPrefix, indexPrefix}
+
+var (
+	backupCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_backup_records",
+	}, []string{"op", "result"})
+)
+
+func derived(key string) bool {
+	for _, prefix := range derivedPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// backedUp picks out the keys an archive holds. pstore's own keys such as counters,
+// sequences and the changelog would go backwards if restored, so only descriptors
+// and namespaced keys are kept from under reservedPrefix.
+func backedUp(key string) bool {
+	if !strings.HasPrefix(key, reservedPrefix) {
+		return true
+	}
+	return strings.HasPrefix(key, descriptorPrefix) || strings.HasPrefix(key, namespacePrefix)
+}
+
+// chunkWriter sends everything written to it as export chunks
+type chunkWriter struct {
+	stream grpc.ServerStreamingServer[pb.ExportChunk]
+}
+
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	if err := c.stream.Send(&pb.ExportChunk{Data: append([]byte{}, p...)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Export streams an archive of every key under the prefix that the caller can read
+func (s *Server) Export(req *pb.ExportRequest, stream grpc.ServerStreamingServer[pb.ExportChunk]) error {
+	buffer := bufio.NewWriterSize(&chunkWriter{stream: stream}, exportChunkSize)
+	if err := s.exportTo(stream.Context(), req.GetPrefix(), buffer); err != nil {
+		return err
+	}
+	return buffer.Flush()
+}
+
+func (s *Server) exportTo(ctx context.Context, prefix string, w io.Writer) error {
+	ns, err := s.namespace(ctx)
+	if err != nil {
+		return err
+	}
+	stored := namespacedKey(ns, prefix)
+	filter, err := s.authorizeList(ctx, stored)
+	if err != nil {
+		return err
+	}
+	keys, err := s.getKeys(ctx, &pb.GetKeysRequest{Prefix: stored, AllKeys: stored == ""})
+	if err != nil {
+		return err
+	}
+
+	archive := gzip.NewWriter(w)
+	for _, key := range filter(keys.GetKeys()) {
+		if !backedUp(key) {
+			continue
+		}
+		if !s.readable(ctx, key) {
+			backupCount.With(prometheus.Labels{"op": "export", "result": "skipped"}).Inc()
+			continue
+		}
+		resp, err := s.read(ctx, &pb.ReadRequest{Key: key})
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				backupCount.With(prometheus.Labels{"op": "export", "result": "skipped"}).Inc()
+				continue
+			}
+			return err
+		}
+		record := &pb.BackupRecord{Key: strings.TrimPrefix(key, namespacedKey(ns, "")), Value: resp.GetValue(), Timestamp: resp.GetTimestamp()}
+		if _, err := protodelim.MarshalTo(archive, record); err != nil {
+			return err
+		}
+		backupCount.With(prometheus.Labels{"op": "export", "result": "written"}).Inc()
+	}
+	return archive.Close()
+}
+
+// readArchive calls fn with each record in the archive read from r
+func readArchive(r io.Reader, fn func(record *pb.BackupRecord) error) error {
+	archive, err := gzip.NewReader(bufio.NewReader(r))
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "bad archive: %v", err)
+	}
+	records := bufio.NewReader(archive)
+
+	count := 0
+	for {
+		record := &pb.BackupRecord{}
+		err := protodelim.UnmarshalOptions{MaxSize: -1}.UnmarshalFrom(records, record)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "bad archive after %v records: %v", count, err)
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+		count++
+	}
+}
+
+// chunkReader reads the archive out of the import requests
+type chunkReader struct {
+	stream grpc.ClientStreamingServer[pb.ImportRequest, pb.ImportResponse]
+	data   []byte
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for len(c.data) == 0 {
+		req, err := c.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		c.data = req.GetData()
+	}
+	n := copy(p, c.data)
+	c.data = c.data[n:]
+	return n, nil
+}
+
+// Import writes every record in an archive as Write would, so the values are encoded
+// for this server and copied to all of its backends. Timestamps are not kept, and
+// records for keys an archive should not hold are skipped.
+func (s *Server) Import(stream grpc.ClientStreamingServer[pb.ImportRequest, pb.ImportResponse]) error {
+	ctx := stream.Context()
+	options, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	ns, err := s.namespace(ctx)
+	if err != nil {
+		return err
+	}
+
+	resp := &pb.ImportResponse{}
+	descriptors := false
+	err = readArchive(&chunkReader{stream: stream, data: options.GetData()}, func(record *pb.BackupRecord) error {
+		key := namespacedKey(ns, record.GetKey())
+		if !backedUp(key) {
+			resp.Skipped++
+			backupCount.With(prometheus.Labels{"op": "import", "result": "skipped"}).Inc()
+			return nil
+		}
+		if err := s.authorize(ctx, rightWrite, key); err != nil {
+			return err
+		}
+
+		if options.GetExisting() == pb.ImportRequest_SKIP {
+			_, err := s.read(ctx, &pb.ReadRequest{Key: key})
+			if err == nil {
+				resp.Skipped++
+				backupCount.With(prometheus.Labels{"op": "import", "result": "skipped"}).Inc()
+				return nil
+			}
+			if status.Code(err) != codes.NotFound {
+				return err
+			}
+		}
+
+		if !options.GetDryRun() {
+			rec := s.newAudit(ctx, "write", key, record.GetValue().GetValue())
+			_, err := s.write(ctx, keyNamespace(key), &pb.WriteRequest{Key: key, Value: record.GetValue()}, rec)
+			s.finishAudit(rec, err)
+			if err != nil {
+				return status.Errorf(status.Code(err), "unable to import %v after %v records: %v", record.GetKey(), resp.GetWritten(), err)
+			}
+			backupCount.With(prometheus.Labels{"op": "import", "result": "written"}).Inc()
+		}
+		resp.Written++
+		descriptors = descriptors || strings.HasPrefix(key, descriptorPrefix)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Imported descriptors only take effect once they are in the registry
+	if descriptors && !options.GetDryRun() {
+		if err := s.loadDescriptors(ctx, ""); err != nil {
+			log.Printf("Unable to load imported descriptors: %v", err)
+		}
+	}
+
+	log.Printf("Imported %v records, skipped %v (dry run: %v)", resp.GetWritten(), resp.GetSkipped(), options.GetDryRun())
+	return stream.SendAndClose(resp)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"testing"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+type testExportStream struct {
+	grpc.ServerStream
+	data bytes.Buffer
+}
+
+func (t *testExportStream) Context() context.Context {
+	return context.Background()
+}
+
+func (t *testExportStream) Send(chunk *pb.ExportChunk) error {
+	t.data.Write(chunk.GetData())
+	return nil
+}
+
+type testImportStream struct {
+	grpc.ServerStream
+	reqs []*pb.ImportRequest
+	resp *pb.ImportResponse
+}
+
+func (t *testImportStream) Context() context.Context {
+	return context.Background()
+}
+
+func (t *testImportStream) Recv() (*pb.ImportRequest, error) {
+	if len(t.reqs) == 0 {
+		return nil, io.EOF
+	}
+	req := t.reqs[0]
+	t.reqs = t.reqs[1:]
+	return req, nil
+}
+
+func (t *testImportStream) SendAndClose(resp *pb.ImportResponse) error {
+	t.resp = resp
+	return nil
+}
+
+// importArchive sends the options and then the archive in small pieces
+func importArchive(s *Server, options *pb.ImportRequest, archive []byte) (*pb.ImportResponse, error) {
+	stream := &testImportStream{reqs: []*pb.ImportRequest{options}}
+	for len(archive) > 0 {
+		n := min(len(archive), 10)
+		stream.reqs = append(stream.reqs, &pb.ImportRequest{Data: archive[:n]})
+		archive = archive[n:]
+	}
+	err := s.Import(stream)
+	return stream.resp, err
+}
+
+func TestExportImport(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"), getTestBackend("secondary"))
+	ctx := context.Background()
+	s.Write(ctx, &pb.WriteRequest{Key: "things/one", Value: &anypb.Any{TypeUrl: "type.googleapis.com/test.Thing", Value: []byte("hello")}})
+	s.Write(ctx, &pb.WriteRequest{Key: "things/two", Value: &anypb.Any{Value: []byte("there")}})
+	s.Write(ctx, &pb.WriteRequest{Key: "other/three", Value: &anypb.Any{Value: []byte("elsewhere")}})
+	s.write(ctx, "", &pb.WriteRequest{Key: indexPrefix + "derived", Value: &anypb.Any{Value: []byte("skip me")}}, nil)
+	s.Count(ctx, &pb.CountRequest{Counter: "hits", Delta: 10})
+
+	stream := &testExportStream{}
+	if err := s.Export(&pb.ExportRequest{}, stream); err != nil {
+		t.Fatalf("Bad export: %v", err)
+	}
+	archive := stream.data.Bytes()
+
+	restored := getTestServer(getTestBackend("primary"), getTestBackend("secondary"))
+	resp, err := importArchive(restored, &pb.ImportRequest{DryRun: true}, archive)
+	if err != nil || resp.GetWritten() != 3 {
+		t.Fatalf("Bad dry run: %v, %v", resp, err)
+	}
+	if _, err := restored.Read(ctx, &pb.ReadRequest{Key: "things/one"}); err == nil {
+		t.Errorf("Dry run wrote a value")
+	}
+
+	restored.Write(ctx, &pb.WriteRequest{Key: "things/two", Value: &anypb.Any{Value: []byte("newer")}})
+	resp, err = importArchive(restored, &pb.ImportRequest{Existing: pb.ImportRequest_SKIP}, archive)
+	if err != nil || resp.GetWritten() != 2 || resp.GetSkipped() != 1 {
+		t.Fatalf("Bad import: %v, %v", resp, err)
+	}
+
+	read, err := restored.Read(ctx, &pb.ReadRequest{Key: "things/one"})
+	if err != nil || string(read.GetValue().GetValue()) != "hello" || read.GetValue().GetTypeUrl() != "type.googleapis.com/test.Thing" {
+		t.Errorf("Bad restored value: %v, %v", read, err)
+	}
+	read, err = restored.Read(ctx, &pb.ReadRequest{Key: "things/two"})
+	if err != nil || string(read.GetValue().GetValue()) != "newer" {
+		t.Errorf("Existing value was not kept: %v, %v", read, err)
+	}
+
+	stream = &testExportStream{}
+	if err := s.Export(&pb.ExportRequest{Prefix: "things/"}, stream); err != nil {
+		t.Fatalf("Bad export: %v", err)
+	}
+	resp, err = importArchive(restored, &pb.ImportRequest{DryRun: true}, stream.data.Bytes())
+	if err != nil || resp.GetWritten() != 2 {
+		t.Errorf("Prefix export has the wrong keys: %v, %v", resp, err)
+	}
+
+	if _, err := importArchive(restored, &pb.ImportRequest{Data: []byte("not an archive")}, nil); err == nil {
+		t.Errorf("Bad archive was imported")
+	}
+}
+
+func TestImportSkipsInternalKeys(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+	ctx := context.Background()
+	s.Count(ctx, &pb.CountRequest{Counter: "hits", Delta: 10})
+
+	// An archive holding pstore's own keys must not overwrite them
+	buffer := &bytes.Buffer{}
+	archive := gzip.NewWriter(buffer)
+	protodelim.MarshalTo(archive, &pb.BackupRecord{Key: counterKey("hits"), Value: &anypb.Any{Value: []byte("stale")}})
+	protodelim.MarshalTo(archive, &pb.BackupRecord{Key: "things/one", Value: &anypb.Any{Value: []byte("hello")}})
+	archive.Close()
+
+	resp, err := importArchive(s, &pb.ImportRequest{}, buffer.Bytes())
+	if err != nil || resp.GetWritten() != 1 || resp.GetSkipped() != 1 {
+		t.Fatalf("Bad import: %v, %v", resp, err)
+	}
+	count, err := s.Count(ctx, &pb.CountRequest{Counter: "hits"})
+	if err != nil || count.GetCount() != 11 {
+		t.Errorf("Counter was damaged by the import: %v, %v", count, err)
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	breakerErrorRate  = flag.Float64("breaker_error_rate", 0.5, "Open a backend's circuit once this fraction of its recent calls fail, zero disables the breakers")
+	breakerLatency    = flag.Duration("breaker_latency", 0, "Count backend calls slower than this as failures, zero only counts errors")
+	breakerWindow     = flag.Int("breaker_window", 20, "Number of recent calls the error rate is taken over")
+	breakerCooldown   = flag.Duration("breaker_cooldown", time.Second*10, "How long a circuit stays open before a probe call is let through")
+	breakerMaxRepairs = flag.Int("breaker_max_repairs", 10000, "Keys held for repair while a circuit is open, later keys are left for read repair")
+)
+
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+var (
+	breakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pstore_breaker_state",
+		Help: "0 closed, 1 open, 2 half open",
+	}, []string{"client"})
+	breakerTrips = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_breaker_trips",
+	}, []string{"client"})
+	breakerSkipped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_breaker_skipped",
+	}, []string{"client"})
+	breakerRepairs = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pstore_breaker_held_repairs",
+	}, []string{"client"})
+	breakerDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_breaker_dropped_repairs",
+	}, []string{"client"})
+)
+
+type breaker struct {
+	state    int
+	outcomes []bool
+	next     int
+	failures int
+	openedAt time.Time
+	probing  bool
+
+	// Writes and deletes skipped while open, latest by key, replayed once the circuit closes
+	held map[string]*WriteElement
+}
+
+// breakers tracks a circuit for each backend by name
+type breakers struct {
+	lock       sync.Mutex
+	errorRate  float64
+	latency    time.Duration
+	window     int
+	cooldown   time.Duration
+	maxRepairs int
+	circuits   map[string]*breaker
+	now        func() time.Time
+}
+
+func newBreakers(errorRate float64, latency time.Duration, window int, cooldown time.Duration, maxRepairs int) *breakers {
+	if errorRate <= 0 || window <= 0 {
+		return nil
+	}
+	return &breakers{
+		errorRate:  errorRate,
+		latency:    latency,
+		window:     window,
+		cooldown:   cooldown,
+		maxRepairs: maxRepairs,
+		circuits:   make(map[string]*breaker),
+		now:        time.Now,
+	}
+}
+
+func (b *breakers) circuit(name string) *breaker {
+	c, ok := b.circuits[name]
+	if !ok {
+		c = &breaker{outcomes: make([]bool, 0, b.window), held: make(map[string]*WriteElement)}
+		b.circuits[name] = c
+	}
+	return c
+}
+
+func (b *breakers) setState(name string, c *breaker, state int) {
+	c.state = state
+	breakerState.With(prometheus.Labels{"client": name}).Set(float64(state))
+}
+
+// allow reports whether a call to the backend can go ahead. Once an open circuit has
+// cooled down a single probe call is let through.
+func (b *breakers) allow(name string) bool {
+	if b == nil {
+		return true
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	c := b.circuit(name)
+	switch c.state {
+	case breakerOpen:
+		if b.now().Sub(c.openedAt) < b.cooldown {
+			break
+		}
+		b.setState(name, c, breakerHalfOpen)
+		c.probing = true
+		return true
+	case breakerHalfOpen:
+		if !c.probing {
+			c.probing = true
+			return true
+		}
+	default:
+		return true
+	}
+	breakerSkipped.With(prometheus.Labels{"client": name}).Inc()
+	return false
+}
+
+// isOpen reports whether calls to the backend are being held back, without using up a probe
+func (b *breakers) isOpen(name string) bool {
+	if b == nil {
+		return false
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.circuit(name).state != breakerClosed
+}
+
+// errAbandoned is the cause pstore cancels a backend call with once it no longer wants
+// the answer, such as a hedge that lost
+var errAbandoned = errors.New("abandoned by pstore")
+
+// abandoned reports whether a call failed only because pstore cancelled it
+func abandoned(ctx context.Context, err error) bool {
+	return status.Code(err) == codes.Canceled && errors.Is(context.Cause(ctx), errAbandoned)
+}
+
+// failed picks out errors that say the backend is unwell, rather than answers like NotFound.
+// A call the caller gave up on counts, a hung backend is often only seen that way.
+func failed(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown, codes.ResourceExhausted, codes.Canceled:
+		return true
+	}
+	return false
+}
+
+// abandon notes a call pstore cancelled itself, which says nothing about the backend.
+// An abandoned probe frees the slot for the next call to probe again.
+func (b *breakers) abandon(name string) {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if c := b.circuit(name); c.state == breakerHalfOpen {
+		c.probing = false
+	}
+}
+
+// record counts the outcome of a call, returning the held repairs if it closed the circuit
+func (b *breakers) record(name string, d time.Duration, err error) []*WriteElement {
+	if b == nil {
+		return nil
+	}
+	bad := failed(err) || (b.latency > 0 && d > b.latency)
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	c := b.circuit(name)
+
+	if c.state == breakerHalfOpen && c.probing {
+		c.probing = false
+		if bad {
+			c.openedAt = b.now()
+			b.setState(name, c, breakerOpen)
+			return nil
+		}
+		log.Printf("Circuit to %v is closed again, replaying %v repairs", name, len(c.held))
+		b.setState(name, c, breakerClosed)
+		c.outcomes, c.next, c.failures = c.outcomes[:0], 0, 0
+		held := make([]*WriteElement, 0, len(c.held))
+		for _, we := range c.held {
+			held = append(held, we)
+		}
+		clear(c.held)
+		breakerRepairs.With(prometheus.Labels{"client": name}).Set(0)
+		return held
+	}
+	if c.state != breakerClosed {
+		return nil
+	}
+
+	if len(c.outcomes) < b.window {
+		c.outcomes = append(c.outcomes, bad)
+	} else {
+		if c.outcomes[c.next] {
+			c.failures--
+		}
+		c.outcomes[c.next] = bad
+		c.next = (c.next + 1) % b.window
+	}
+	if bad {
+		c.failures++
+	}
+
+	if len(c.outcomes) == b.window && float64(c.failures)/float64(b.window) >= b.errorRate {
+		log.Printf("Circuit to %v is open, %v of the last %v calls failed", name, c.failures, b.window)
+		breakerTrips.With(prometheus.Labels{"client": name}).Inc()
+		c.openedAt = b.now()
+		b.setState(name, c, breakerOpen)
+	}
+	return nil
+}
+
+// hold keeps a write or delete for the backend until its circuit closes
+func (b *breakers) hold(name string, we *WriteElement) {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	c := b.circuit(name)
+	if _, ok := c.held[we.key]; !ok && len(c.held) >= b.maxRepairs {
+		breakerDropped.With(prometheus.Labels{"client": name}).Inc()
+		return
+	}
+	c.held[we.key] = we
+	breakerRepairs.With(prometheus.Labels{"client": name}).Set(float64(len(c.held)))
+}
+
+// checkBackend is called before each backend call, failing fast while the backend's circuit is open
+func (s *Server) checkBackend(client pstore) error {
+	if !s.breakers.allow(client.Name()) {
+		return status.Errorf(codes.Unavailable, "circuit to %v is open", client.Name())
+	}
+	return nil
+}
+
+// recordBackend is called after each backend call, queueing held repairs when a circuit closes
+func (s *Server) recordBackend(ctx context.Context, client pstore, d time.Duration, err error) {
+	if abandoned(ctx, err) {
+		s.breakers.abandon(client.Name())
+		return
+	}
+	if held := s.breakers.record(client.Name(), d, err); len(held) > 0 {
+		go func() {
+			for _, we := range held {
+				s.wq <- we
+			}
+		}()
+	}
+}
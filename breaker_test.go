@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func heldRepairs(b *breakers, name string) int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return len(b.circuit(name).held)
+}
+
+func TestBreakerTrips(t *testing.T) {
+	b := newBreakers(0.5, time.Millisecond*100, 4, time.Hour, 10)
+	down := status.Errorf(codes.Unavailable, "down")
+
+	b.record("redis", time.Millisecond, nil)
+	b.abandon("redis")
+	b.record("redis", time.Millisecond, status.Errorf(codes.NotFound, "missing"))
+	b.record("redis", time.Second, nil)
+	if b.isOpen("redis") {
+		t.Fatalf("Circuit opened before the window filled")
+	}
+	b.record("redis", time.Millisecond, down)
+	if !b.isOpen("redis") || b.allow("redis") {
+		t.Fatalf("Slow and failed calls did not open the circuit")
+	}
+	if !b.allow("pgstore") {
+		t.Errorf("Circuit for another backend is open")
+	}
+
+	b.now = func() time.Time { return time.Now().Add(time.Hour) }
+	if !b.allow("redis") || b.allow("redis") {
+		t.Fatalf("Half open circuit should allow exactly one probe")
+	}
+	b.record("redis", time.Millisecond, down)
+	if b.allow("redis") {
+		t.Errorf("Failed probe did not reopen the circuit")
+	}
+
+	b.now = func() time.Time { return time.Now().Add(time.Hour * 2) }
+	b.allow("redis")
+	b.abandon("redis")
+	if !b.isOpen("redis") || !b.allow("redis") {
+		t.Errorf("Abandoned probe should leave the circuit half open for another probe")
+	}
+	b.record("redis", time.Millisecond, down)
+
+	b.hold("redis", &WriteElement{key: "things/one"})
+	b.hold("redis", &WriteElement{key: "things/one", delete: true})
+	b.now = func() time.Time { return time.Now().Add(time.Hour * 3) }
+	b.allow("redis")
+	held := b.record("redis", time.Millisecond, nil)
+	if b.isOpen("redis") || len(held) != 1 || !held[0].delete {
+		t.Errorf("Good probe did not close the circuit and hand back the latest repair: %v", held)
+	}
+}
+
+func TestBreakerCancellations(t *testing.T) {
+	backend := getTestBackend("redis")
+	backend.readDelay = time.Hour
+	s := getTestServer(backend)
+	s.breakers = newBreakers(0.5, 0, 2, time.Hour, 10)
+
+	// Calls pstore gave up on itself never count
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(errAbandoned)
+	for i := 0; i < 4; i++ {
+		s.runRead(ctx, backend, &pb.ReadRequest{Key: "things/one"})
+	}
+	if s.breakers.isOpen("redis") {
+		t.Fatalf("Abandoned calls opened the circuit")
+	}
+
+	// Calls the caller gave up on do
+	ctx, cancel = context.WithCancelCause(context.Background())
+	cancel(nil)
+	for i := 0; i < 2; i++ {
+		s.runRead(ctx, backend, &pb.ReadRequest{Key: "things/one"})
+	}
+	if !s.breakers.isOpen("redis") {
+		t.Errorf("Calls cancelled by the caller did not open the circuit")
+	}
+}
+
+func TestBreakerHoldsRepairs(t *testing.T) {
+	secondary := getTestBackend("secondary")
+	s := getTestServer(getTestBackend("primary"), secondary)
+	s.breakers = newBreakers(0.5, 0, 2, time.Millisecond*50, 10)
+	ctx := context.Background()
+
+	secondary.down = true
+	for _, key := range []string{"things/one", "things/two", "things/three"} {
+		if _, err := s.Write(ctx, &pb.WriteRequest{Key: key, Value: &anypb.Any{Value: []byte(key)}}); err != nil {
+			t.Fatalf("Write failed with a secondary down: %v", err)
+		}
+	}
+	if !s.breakers.isOpen("secondary") {
+		t.Fatalf("Circuit did not open")
+	}
+	if _, err := s.Delete(ctx, &pb.DeleteRequest{Key: "things/one"}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	for heldRepairs(s.breakers, "secondary") != 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The read's check against the secondary is the probe that closes the circuit
+	secondary.down = false
+	time.Sleep(time.Millisecond * 60)
+	if got := valueOf(s, "things/two"); got != "things/two" {
+		t.Fatalf("Bad read: %v", got)
+	}
+	for drained := false; !drained; {
+		select {
+		case we := <-s.wq:
+			s.runElem(we)
+		case <-time.After(time.Millisecond * 200):
+			drained = true
+		}
+	}
+
+	for key, want := range map[string]codes.Code{"things/one": codes.NotFound, "things/two": codes.OK, "things/three": codes.OK} {
+		_, err := secondary.Read(ctx, &pb.ReadRequest{Key: key})
+		if status.Code(err) != want {
+			t.Errorf("Repair of %v left %v, want %v", key, err, want)
+		}
+	}
+}
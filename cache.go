@@ -0,0 +1,190 @@
+package main
+
+import (
+	"container/list"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+var (
+	cacheBytes = flag.Int64("cache_bytes", 0, "Size of the read cache, zero disables it. Only writes through this process invalidate it.")
+	cacheTTL   = flag.Duration("cache_ttl", 0, "How long cached values are served, zero keeps them until they are evicted")
+	cacheTTLs  = flag.String("cache_ttls", "", "Per prefix cache TTLs, e.g. config/=1m,sessions/=5s")
+)
+
+var (
+	cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_cache_lookups",
+	}, []string{"result"})
+	cacheEvictions = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pstore_cache_evictions",
+	})
+	cacheSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pstore_cache_bytes",
+	})
+)
+
+type cacheEntry struct {
+	key     string
+	value   *anypb.Any
+	stamp   int64
+	size    int64
+	expires time.Time
+}
+
+// readCache is an LRU of decoded values by stored key, bounded by the bytes it holds
+type readCache struct {
+	lock     sync.Mutex
+	maxBytes int64
+	size     int64
+	ttl      time.Duration
+	ttls     map[string]time.Duration
+	entries  map[string]*list.Element
+	order    *list.List
+	now      func() time.Time
+
+	// Bumped by every invalidation of a key in the shard, so a read that raced a write
+	// does not cache what it saw and one key's writes don't stop the rest being cached
+	epochs [cacheShards]uint64
+}
+
+// Keys are spread over this many invalidation epochs
+const cacheShards = 256
+
+func cacheShard(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % cacheShards)
+}
+
+func parseCache(maxBytes int64, ttl time.Duration, ttls string) (*readCache, error) {
+	if maxBytes <= 0 {
+		return nil, nil
+	}
+	c := &readCache{
+		maxBytes: maxBytes,
+		ttl:      ttl,
+		ttls:     make(map[string]time.Duration),
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+	for _, entry := range strings.Split(ttls, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("bad cache ttl %q", entry)
+		}
+		d, err := time.ParseDuration(parts[1])
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("bad cache ttl %q for prefix %v", parts[1], parts[0])
+		}
+		c.ttls[parts[0]] = d
+	}
+	return c, nil
+}
+
+// ttlFor returns the TTL of the longest matching prefix, or the default
+func (c *readCache) ttlFor(key string) time.Duration {
+	best := -1
+	ttl := c.ttl
+	for prefix, d := range c.ttls {
+		if strings.HasPrefix(key, prefix) && len(prefix) > best {
+			best = len(prefix)
+			ttl = d
+		}
+	}
+	return ttl
+}
+
+func (c *readCache) remove(e *list.Element) {
+	entry := c.order.Remove(e).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+	cacheSize.Set(float64(c.size))
+}
+
+func (c *readCache) get(key string) (*pb.ReadResponse, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		cacheLookups.With(prometheus.Labels{"result": "miss"}).Inc()
+		return nil, false
+	}
+	entry := e.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && c.now().After(entry.expires) {
+		c.remove(e)
+		cacheLookups.With(prometheus.Labels{"result": "expired"}).Inc()
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	cacheLookups.With(prometheus.Labels{"result": "hit"}).Inc()
+	return &pb.ReadResponse{Value: proto.Clone(entry.value).(*anypb.Any), Timestamp: entry.stamp}, true
+}
+
+// version is read for key before going to the backends and handed back to put
+func (c *readCache) version(key string) uint64 {
+	if c == nil {
+		return 0
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.epochs[cacheShard(key)]
+}
+
+// put caches a value read from the backends, unless its shard was invalidated since version
+func (c *readCache) put(key string, resp *pb.ReadResponse, version uint64) {
+	if c == nil {
+		return
+	}
+	size := int64(len(key) + len(resp.GetValue().GetTypeUrl()) + len(resp.GetValue().GetValue()))
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.epochs[cacheShard(key)] != version || size > c.maxBytes {
+		return
+	}
+
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+	entry := &cacheEntry{key: key, value: proto.Clone(resp.GetValue()).(*anypb.Any), stamp: resp.GetTimestamp(), size: size}
+	if ttl := c.ttlFor(key); ttl > 0 {
+		entry.expires = c.now().Add(ttl)
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	c.size += size
+	for c.size > c.maxBytes {
+		c.remove(c.order.Back())
+		cacheEvictions.Inc()
+	}
+	cacheSize.Set(float64(c.size))
+}
+
+func (c *readCache) invalidate(key string) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.epochs[cacheShard(key)]++
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+}
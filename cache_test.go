@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestCache(t *testing.T) {
+	primary := getTestBackend("primary")
+	s := getTestServer(primary, getTestBackend("secondary"))
+	cache, err := parseCache(1024, 0, "")
+	if err != nil {
+		t.Fatalf("Bad cache: %v", err)
+	}
+	s.cache = cache
+	ctx := context.Background()
+
+	s.Write(ctx, &pb.WriteRequest{Key: "things/one", Value: &anypb.Any{Value: []byte("hello")}})
+	valueOf(s, "things/one")
+
+	primary.down = true
+	if got := valueOf(s, "things/one"); got != "hello" {
+		t.Errorf("Cached read went to the backend: %v", got)
+	}
+	primary.down = false
+
+	s.Write(ctx, &pb.WriteRequest{Key: "things/one", Value: &anypb.Any{Value: []byte("there")}})
+	if got := valueOf(s, "things/one"); got != "there" {
+		t.Errorf("Write did not invalidate the cache: %v", got)
+	}
+	s.Delete(ctx, &pb.DeleteRequest{Key: "things/one"})
+	if got := valueOf(s, "things/one"); got != "NotFound" {
+		t.Errorf("Delete did not invalidate the cache: %v", got)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	c, _ := parseCache(100, 0, "")
+	for _, key := range []string{"a", "b", "c"} {
+		c.put(key, &pb.ReadResponse{Value: &anypb.Any{Value: make([]byte, 29)}}, c.version(key))
+	}
+	c.get("a")
+	c.put("d", &pb.ReadResponse{Value: &anypb.Any{Value: make([]byte, 29)}}, c.version("d"))
+
+	if _, ok := c.get("b"); ok {
+		t.Errorf("Least recently used entry was kept")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Errorf("Recently used entry was evicted")
+	}
+	if c.size > 100 {
+		t.Errorf("Cache is over its size: %v", c.size)
+	}
+
+	version := c.version("e")
+	c.invalidate("e")
+	c.put("e", &pb.ReadResponse{Value: &anypb.Any{}}, version)
+	if _, ok := c.get("e"); ok {
+		t.Errorf("Value read before an invalidation was cached")
+	}
+
+	// Writes elsewhere in the store don't stop a key being cached
+	var other string
+	for i := 0; other == ""; i++ {
+		if key := fmt.Sprintf("other/%v", i); cacheShard(key) != cacheShard("f") {
+			other = key
+		}
+	}
+	version = c.version("f")
+	c.invalidate(other)
+	c.put("f", &pb.ReadResponse{Value: &anypb.Any{}}, version)
+	if _, ok := c.get("f"); !ok {
+		t.Errorf("Invalidating %v stopped f being cached", other)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	c, err := parseCache(1024, time.Hour, "sessions/=5s")
+	if err != nil {
+		t.Fatalf("Bad cache: %v", err)
+	}
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	c.put("sessions/one", &pb.ReadResponse{Value: &anypb.Any{}}, c.version("sessions/one"))
+	c.put("config/one", &pb.ReadResponse{Value: &anypb.Any{}}, c.version("config/one"))
+
+	now = now.Add(time.Minute)
+	if _, ok := c.get("sessions/one"); ok {
+		t.Errorf("Expired entry was served")
+	}
+	if _, ok := c.get("config/one"); !ok {
+		t.Errorf("Entry expired early")
+	}
+
+	if _, err := parseCache(1024, 0, "sessions/=soon"); err == nil {
+		t.Errorf("Bad TTL was accepted")
+	}
+}
@@ -0,0 +1,512 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	// Changelog events are stored under this prefix by sequence number
+	changelogPrefix = reservedPrefix + "changelog/"
+
+	// Events that could not be stored are marked under this prefix by sequence number
+	changelogGapPrefix = reservedPrefix + "changelog_gaps/"
+
+	// The sequence changelog numbers are leased from, it is not itself a change
+	changelogSequence = reservedPrefix + "changelog_sequence"
+
+	// Sequence numbers are leased from the backends in blocks of this size
+	changelogBlock = 1000
+
+	// Followers this far behind are dropped and have to resume
+	changelogBuffer = 1000
+)
+
+var (
+	changelogEnabled   = flag.Bool("changelog", false, "Record every mutation in a changelog that can be streamed with Changes")
+	changelogRetention = flag.Duration("changelog_retention", time.Hour*24*7, "How long changelog events are kept")
+)
+
+var (
+	changeCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_changelog_events",
+	}, []string{"op", "result"})
+	changeGaps = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pstore_changelog_gaps",
+	})
+	changeFollowers = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pstore_changelog_followers",
+	})
+	changeDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pstore_changelog_dropped_followers",
+	})
+	changePurged = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pstore_changelog_purged",
+	})
+)
+
+// pendingChange is an event that has been numbered and handed to followers but is
+// still being stored, done is closed once it has been stored or marked as a gap
+type pendingChange struct {
+	event *pb.ChangeEvent
+	done  chan struct{}
+}
+
+func changeKey(sequence int64) string {
+	return fmt.Sprintf("%v%020d", changelogPrefix, sequence)
+}
+
+func gapKey(sequence int64) string {
+	return fmt.Sprintf("%v%020d", changelogGapPrefix, sequence)
+}
+
+// nextSequence hands out a changelog sequence number, leasing a new block once
+// the current one runs out. Must hold the change lock.
+func (s *Server) nextSequence(ctx context.Context) (int64, error) {
+	for s.changeNext == 0 || s.changeNext > s.changeLast {
+		resp, err := s.allocateRange(ctx, "changelog", changelogSequence, changelogBlock, nil)
+		if err != nil {
+			return 0, err
+		}
+		// The number given to events lost while the lease failed is never reused
+		s.changeNext, s.changeLast = max(resp.GetFirst(), s.changeLost+1), resp.GetLast()
+	}
+	sequence := s.changeNext
+	s.changeNext++
+	return sequence, nil
+}
+
+// recordChange appends a mutation that has succeeded to the changelog and hands it to
+// followers. The event is stored in the background, one that can't be stored is logged
+// and leaves a gap that Changes and Restore refuse to read across.
+func (s *Server) recordChange(ctx context.Context, op pb.ChangeEvent_Op, key string, value *anypb.Any) {
+	if !s.changelog || key == changelogSequence {
+		return
+	}
+	event := &pb.ChangeEvent{Timestamp: time.Now().UnixNano(), Op: op, Key: key, Value: value, Caller: callerFrom(ctx)}
+
+	// Don't lose the event because the caller went away once their write was done
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+
+	s.changeLock.Lock()
+	defer s.changeLock.Unlock()
+	sequence, err := s.nextSequence(ctx)
+	if err != nil {
+		cancel()
+		// Without a number followers never see the event, so they are dropped to resume
+		// into the gap. Every event lost before the next lease shares one number.
+		s.changeLost = max(s.changeNext, 1)
+		event.Sequence = s.changeLost
+		if gap := s.addGap(event, err); gap != nil {
+			go s.markGap(gap)
+		}
+		for f := range s.followers {
+			close(f)
+			delete(s.followers, f)
+			changeDropped.Inc()
+		}
+		changeFollowers.Set(0)
+		return
+	}
+	event.Sequence = sequence
+
+	pending := &pendingChange{event: event, done: make(chan struct{})}
+	if s.pending == nil {
+		s.pending = make(map[int64]*pendingChange)
+	}
+	s.pending[sequence] = pending
+	for f := range s.followers {
+		select {
+		case f <- event:
+		default:
+			close(f)
+			delete(s.followers, f)
+			changeDropped.Inc()
+			changeFollowers.Set(float64(len(s.followers)))
+		}
+	}
+
+	go func() {
+		defer cancel()
+		err := s.storeChange(ctx, changeKey(sequence), event)
+		var gap *pb.ChangeEvent
+		s.changeLock.Lock()
+		delete(s.pending, sequence)
+		if err != nil {
+			gap = s.addGap(event, err)
+		}
+		s.changeLock.Unlock()
+		if gap != nil {
+			s.markGap(gap)
+		}
+		close(pending.done)
+	}()
+}
+
+// addGap notes that event could not be stored, returning the gap it leaves unless
+// one is already known at that number. Must hold the change lock.
+func (s *Server) addGap(event *pb.ChangeEvent, err error) *pb.ChangeEvent {
+	changeCount.With(prometheus.Labels{"op": event.GetOp().String(), "result": fmt.Sprintf("%v", status.Code(err))}).Inc()
+	log.Printf("Unable to record %v of %v in the changelog, leaving a gap at %v: %v", event.GetOp(), event.GetKey(), event.GetSequence(), err)
+	changeGaps.Inc()
+
+	if s.gaps == nil {
+		s.gaps = make(map[int64]*pb.ChangeEvent)
+	}
+	if _, ok := s.gaps[event.GetSequence()]; ok {
+		return nil
+	}
+	gap := &pb.ChangeEvent{Sequence: event.GetSequence(), Timestamp: event.GetTimestamp(), Op: event.GetOp(), Key: event.GetKey(), Caller: event.GetCaller()}
+	s.gaps[gap.GetSequence()] = gap
+	return gap
+}
+
+// markGap stores a gap in the backends so it outlives this process, it keeps
+// everything about the lost event but its value
+func (s *Server) markGap(gap *pb.ChangeEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := s.storeChange(ctx, gapKey(gap.GetSequence()), gap); err != nil {
+		log.Printf("Unable to mark changelog gap %v: %v", gap.GetSequence(), err)
+	}
+}
+
+// storeChange writes event to every backend under key, the value sealed as it
+// would be under the changed key
+func (s *Server) storeChange(ctx context.Context, key string, event *pb.ChangeEvent) error {
+	stored := event
+	if event.GetValue() != nil {
+		sealed, err := s.keyring.encrypt(event.GetKey(), event.GetValue().GetValue())
+		if err != nil {
+			return err
+		}
+		stored = proto.Clone(event).(*pb.ChangeEvent)
+		stored.Value.Value = sealed
+	}
+	data, err := anypb.New(stored)
+	if err != nil {
+		return err
+	}
+	value, err := s.encodeValue(key, data)
+	if err != nil {
+		return err
+	}
+	_, err = s.writeAll(ctx, &pb.WriteRequest{Key: key, Value: &anypb.Any{TypeUrl: data.GetTypeUrl(), Value: value}}, nil)
+	if err == nil {
+		changeCount.With(prometheus.Labels{"op": event.GetOp().String(), "result": "OK"}).Inc()
+	}
+	return err
+}
+
+// readChange reads an event or gap marker straight from the primary, so the
+// changelog never displaces values in the read cache
+func (s *Server) readChange(ctx context.Context, key string) (*pb.ChangeEvent, error) {
+	resp, err := s.runRead(ctx, s.clients[0], &pb.ReadRequest{Key: key})
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.decodeValue(key, resp.GetValue())
+	if err != nil {
+		return nil, status.Errorf(codes.DataLoss, "bad changelog event %v: %v", key, err)
+	}
+	event := &pb.ChangeEvent{}
+	if err := proto.Unmarshal(data.GetValue(), event); err != nil {
+		return nil, status.Errorf(codes.DataLoss, "bad changelog event %v: %v", key, err)
+	}
+	if event.GetValue() != nil {
+		value, err := s.keyring.decrypt(event.GetKey(), event.GetValue().GetValue())
+		if err != nil {
+			return nil, status.Errorf(codes.DataLoss, "unable to decrypt changelog event %v: %v", key, err)
+		}
+		event.Value.Value = value
+	}
+	return event, nil
+}
+
+// sequenceKeys lists the keys under prefix numbered after the given sequence, oldest first
+func (s *Server) sequenceKeys(ctx context.Context, prefix string, after int64) ([]string, error) {
+	keys, err := s.runGetKeys(ctx, s.clients[0], &pb.GetKeysRequest{Prefix: prefix})
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, key := range keys.GetKeys() {
+		sequence, err := strconv.ParseInt(strings.TrimPrefix(key, prefix), 10, 64)
+		if err == nil && sequence > after {
+			result = append(result, key)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// changeKeys lists the stored events after the given sequence number, oldest first
+func (s *Server) changeKeys(ctx context.Context, after int64) ([]string, error) {
+	return s.sequenceKeys(ctx, changelogPrefix, after)
+}
+
+// changeGapsAfter returns the gaps after the given sequence number, oldest first, both
+// those marked in the backends and those this process has not managed to mark
+func (s *Server) changeGapsAfter(ctx context.Context, after int64) ([]*pb.ChangeEvent, error) {
+	keys, err := s.sequenceKeys(ctx, changelogGapPrefix, after)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[int64]*pb.ChangeEvent)
+	for _, key := range keys {
+		gap, err := s.readChange(ctx, key)
+		if status.Code(err) == codes.NotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found[gap.GetSequence()] = gap
+	}
+
+	s.changeLock.Lock()
+	for sequence, gap := range s.gaps {
+		if sequence > after {
+			found[sequence] = gap
+		}
+	}
+	s.changeLock.Unlock()
+
+	gaps := make([]*pb.ChangeEvent, 0, len(found))
+	for _, gap := range found {
+		gaps = append(gaps, gap)
+	}
+	sort.Slice(gaps, func(i, j int) bool {
+		return gaps[i].GetSequence() < gaps[j].GetSequence()
+	})
+	return gaps, nil
+}
+
+// pendingChanges returns the events still being stored, oldest first. Must hold the change lock.
+func (s *Server) pendingChanges() []*pendingChange {
+	pending := make([]*pendingChange, 0, len(s.pending))
+	for _, p := range s.pending {
+		pending = append(pending, p)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].event.GetSequence() < pending[j].event.GetSequence()
+	})
+	return pending
+}
+
+// waitForChanges returns once every event recorded so far has been stored or marked as a gap
+func (s *Server) waitForChanges(ctx context.Context) error {
+	s.changeLock.Lock()
+	pending := s.pendingChanges()
+	s.changeLock.Unlock()
+	for _, p := range pending {
+		select {
+		case <-p.done:
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+	return nil
+}
+
+// follow snapshots the events still being stored and, if live is set, starts following
+// the ones recorded after them
+func (s *Server) follow(live bool) (chan *pb.ChangeEvent, []*pb.ChangeEvent) {
+	s.changeLock.Lock()
+	defer s.changeLock.Unlock()
+	var pending []*pb.ChangeEvent
+	for _, p := range s.pendingChanges() {
+		pending = append(pending, p.event)
+	}
+	if !live {
+		return nil, pending
+	}
+	if s.followers == nil {
+		s.followers = make(map[chan *pb.ChangeEvent]bool)
+	}
+	f := make(chan *pb.ChangeEvent, changelogBuffer)
+	s.followers[f] = true
+	changeFollowers.Set(float64(len(s.followers)))
+	return f, pending
+}
+
+func (s *Server) unfollow(f chan *pb.ChangeEvent) {
+	s.changeLock.Lock()
+	defer s.changeLock.Unlock()
+	if s.followers[f] {
+		close(f)
+		delete(s.followers, f)
+		changeFollowers.Set(float64(len(s.followers)))
+	}
+}
+
+func lostChange(gap *pb.ChangeEvent) error {
+	return status.Errorf(codes.DataLoss, "%v of %v at %v is missing from the changelog, resume after %v", gap.GetOp(), gap.GetKey(), gap.GetSequence(), gap.GetSequence())
+}
+
+func (s *Server) Changes(req *pb.ChangesRequest, stream grpc.ServerStreamingServer[pb.ChangeEvent]) error {
+	if !s.changelog {
+		return status.Errorf(codes.FailedPrecondition, "the changelog is not enabled")
+	}
+	ctx := stream.Context()
+	ns, err := s.namespace(ctx)
+	if err != nil {
+		return err
+	}
+	prefix := namespacedKey(ns, req.GetPrefix())
+	if _, err := s.authorizeList(ctx, prefix); err != nil {
+		return err
+	}
+
+	// Follow before reading back so nothing recorded in between is missed, events
+	// still being stored are picked up from the snapshot taken alongside
+	live, pending := s.follow(req.GetFollow())
+	if live != nil {
+		defer s.unfollow(live)
+	}
+
+	last := req.GetAfterSequence()
+	gaps, err := s.changeGapsAfter(ctx, last)
+	if err != nil {
+		return err
+	}
+	send := func(event *pb.ChangeEvent) error {
+		if event.GetSequence() <= last {
+			return nil
+		}
+		if len(gaps) > 0 && event.GetSequence() >= gaps[0].GetSequence() {
+			return lostChange(gaps[0])
+		}
+		last = event.GetSequence()
+		if !strings.HasPrefix(event.GetKey(), prefix) || !s.readable(ctx, event.GetKey()) {
+			return nil
+		}
+		if ns != "" {
+			event = proto.Clone(event).(*pb.ChangeEvent)
+			event.Key = strings.TrimPrefix(event.GetKey(), namespacedKey(ns, ""))
+		}
+		return stream.Send(event)
+	}
+	sendPending := func(before int64) error {
+		for len(pending) > 0 && pending[0].GetSequence() < before {
+			if err := send(pending[0]); err != nil {
+				return err
+			}
+			pending = pending[1:]
+		}
+		return nil
+	}
+
+	keys, err := s.changeKeys(ctx, last)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		event, err := s.readChange(ctx, key)
+		if status.Code(err) == codes.NotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := sendPending(event.GetSequence()); err != nil {
+			return err
+		}
+		if err := send(event); err != nil {
+			return err
+		}
+	}
+	if err := sendPending(math.MaxInt64); err != nil {
+		return err
+	}
+	if len(gaps) > 0 {
+		return lostChange(gaps[0])
+	}
+
+	if live == nil {
+		return nil
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case event, ok := <-live:
+			if !ok {
+				return status.Errorf(codes.Aborted, "fell behind the changelog, resume after %v", last)
+			}
+			if err := send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// purgeChanges deletes events and gaps recorded before the cutoff, stopping at the first one after it
+func (s *Server) purgeChanges(ctx context.Context, cutoff time.Time) (int, error) {
+	// An event still being stored would be left behind the first one kept
+	if err := s.waitForChanges(ctx); err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, prefix := range []string{changelogPrefix, changelogGapPrefix} {
+		keys, err := s.sequenceKeys(ctx, prefix, 0)
+		if err != nil {
+			return count, err
+		}
+		for _, key := range keys {
+			event, err := s.readChange(ctx, key)
+			if status.Code(err) == codes.NotFound {
+				continue
+			}
+			if err != nil {
+				return count, err
+			}
+			if event.GetTimestamp() >= cutoff.UnixNano() {
+				break
+			}
+			if _, err := s.deleteAll(ctx, &pb.DeleteRequest{Key: key}, nil); err != nil {
+				return count, err
+			}
+			if prefix == changelogPrefix {
+				changePurged.Inc()
+				count++
+			}
+		}
+	}
+
+	s.changeLock.Lock()
+	for sequence, gap := range s.gaps {
+		if gap.GetTimestamp() < cutoff.UnixNano() {
+			delete(s.gaps, sequence)
+		}
+	}
+	s.changeLock.Unlock()
+	return count, nil
+}
+
+func (s *Server) runChangelogPurge() {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+		count, err := s.purgeChanges(ctx, time.Now().Add(-*changelogRetention))
+		cancel()
+		log.Printf("Purged %v changelog events: %v", count, err)
+
+		time.Sleep(time.Hour)
+	}
+}
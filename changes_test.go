@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+type testChangeStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent chan *pb.ChangeEvent
+}
+
+func (t *testChangeStream) Context() context.Context {
+	return t.ctx
+}
+
+func (t *testChangeStream) Send(event *pb.ChangeEvent) error {
+	t.sent <- event
+	return nil
+}
+
+func changes(s *Server, req *pb.ChangesRequest) ([]*pb.ChangeEvent, error) {
+	stream := &testChangeStream{ctx: context.Background(), sent: make(chan *pb.ChangeEvent, 100)}
+	err := s.Changes(req, stream)
+	close(stream.sent)
+	var events []*pb.ChangeEvent
+	for event := range stream.sent {
+		events = append(events, event)
+	}
+	return events, err
+}
+
+func TestChanges(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"), getTestBackend("secondary"))
+	if _, err := changes(s, &pb.ChangesRequest{}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Changes without a changelog should fail: %v", err)
+	}
+	s.changelog = true
+
+	ctx := context.Background()
+	s.Write(ctx, &pb.WriteRequest{Key: "things/one", Value: &anypb.Any{Value: []byte("hello")}})
+	s.Write(ctx, &pb.WriteRequest{Key: "other/two", Value: &anypb.Any{Value: []byte("there")}})
+	s.Delete(ctx, &pb.DeleteRequest{Key: "things/one"})
+	s.Count(ctx, &pb.CountRequest{Counter: "hits"})
+
+	// The first count also records the counter being seeded from the native one
+	events, err := changes(s, &pb.ChangesRequest{})
+	if err != nil || len(events) != 5 {
+		t.Fatalf("Bad changes: %v, %v", events, err)
+	}
+	if events[0].GetOp() != pb.ChangeEvent_WRITE || string(events[0].GetValue().GetValue()) != "hello" {
+		t.Errorf("Bad write event: %v", events[0])
+	}
+	if events[2].GetOp() != pb.ChangeEvent_DELETE || events[2].GetKey() != "things/one" {
+		t.Errorf("Bad delete event: %v", events[2])
+	}
+	if events[3].GetOp() != pb.ChangeEvent_COUNTER || events[3].GetKey() != counterKey("hits") {
+		t.Errorf("Bad counter event: %v", events[3])
+	}
+	for i := 1; i < len(events); i++ {
+		if events[i].GetSequence() <= events[i-1].GetSequence() {
+			t.Errorf("Events out of order: %v", events)
+		}
+	}
+
+	resumed, err := changes(s, &pb.ChangesRequest{AfterSequence: events[1].GetSequence(), Prefix: "things/"})
+	if err != nil || len(resumed) != 1 || resumed[0].GetOp() != pb.ChangeEvent_DELETE {
+		t.Errorf("Bad resume: %v, %v", resumed, err)
+	}
+
+	purged, err := s.purgeChanges(ctx, time.Unix(0, events[2].GetTimestamp()))
+	if err != nil || purged != 2 {
+		t.Errorf("Bad purge: %v, %v", purged, err)
+	}
+	left, err := changes(s, &pb.ChangesRequest{})
+	if err != nil || len(left) != 3 {
+		t.Errorf("Purge left the wrong events: %v, %v", left, err)
+	}
+}
+
+func TestChangesFollow(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+	s.changelog = true
+	s.Write(context.Background(), &pb.WriteRequest{Key: "things/one", Value: &anypb.Any{Value: []byte("hello")}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &testChangeStream{ctx: ctx, sent: make(chan *pb.ChangeEvent, 100)}
+	done := make(chan error)
+	go func() {
+		done <- s.Changes(&pb.ChangesRequest{Follow: true}, stream)
+	}()
+
+	if event := <-stream.sent; event.GetKey() != "things/one" {
+		t.Errorf("Bad backfilled event: %v", event)
+	}
+	s.Write(context.Background(), &pb.WriteRequest{Key: "things/two", Value: &anypb.Any{Value: []byte("there")}})
+	if event := <-stream.sent; event.GetKey() != "things/two" {
+		t.Errorf("Bad followed event: %v", event)
+	}
+
+	cancel()
+	if err := <-done; status.Code(err) != codes.Canceled {
+		t.Errorf("Cancelled follow returned %v", err)
+	}
+}
+
+func TestChangesAreEncrypted(t *testing.T) {
+	primary := getTestBackend("primary")
+	s := getTestServer(primary)
+	s.changelog = true
+	s.keyring = testKeyring(t, "old")
+	ctx := context.Background()
+
+	if _, err := s.Write(ctx, &pb.WriteRequest{Key: "things/one", Value: &anypb.Any{Value: []byte("secret")}}); err != nil {
+		t.Fatalf("Bad write: %v", err)
+	}
+	s.waitForChanges(ctx)
+	keys, err := s.changeKeys(ctx, 0)
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("Bad changelog: %v, %v", keys, err)
+	}
+	raw, err := primary.Read(ctx, &pb.ReadRequest{Key: keys[0]})
+	if err != nil || bytes.Contains(raw.GetValue().GetValue(), []byte("secret")) {
+		t.Fatalf("Changelog holds the value in the clear: %v, %v", raw, err)
+	}
+
+	events, err := changes(s, &pb.ChangesRequest{})
+	if err != nil || len(events) != 1 || string(events[0].GetValue().GetValue()) != "secret" {
+		t.Errorf("Bad events: %v, %v", events, err)
+	}
+}
+
+func TestChangeFailuresLeaveGaps(t *testing.T) {
+	primary := getTestBackend("primary")
+	s := getTestServer(primary)
+	s.changelog = true
+	ctx := context.Background()
+
+	if _, err := s.Write(ctx, &pb.WriteRequest{Key: "things/one", Value: &anypb.Any{Value: []byte("hello")}}); err != nil {
+		t.Fatalf("Bad write: %v", err)
+	}
+	s.waitForChanges(ctx)
+
+	// The event is numbered but the backend is gone by the time it is stored
+	primary.down = true
+	s.recordChange(ctx, pb.ChangeEvent_WRITE, "things/two", &anypb.Any{Value: []byte("there")})
+	s.waitForChanges(ctx)
+	primary.down = false
+
+	if _, err := s.Write(ctx, &pb.WriteRequest{Key: "things/three", Value: &anypb.Any{Value: []byte("again")}}); err != nil {
+		t.Fatalf("Bad write: %v", err)
+	}
+	events, err := changes(s, &pb.ChangesRequest{})
+	if status.Code(err) != codes.DataLoss || len(events) != 1 || events[0].GetKey() != "things/one" {
+		t.Fatalf("Changes read across the gap: %v, %v", events, err)
+	}
+	resumed, err := changes(s, &pb.ChangesRequest{AfterSequence: events[0].GetSequence() + 1})
+	if err != nil || len(resumed) != 1 || resumed[0].GetKey() != "things/three" {
+		t.Errorf("Bad resume past the gap: %v, %v", resumed, err)
+	}
+}
+
+func TestChangeFailuresDoNotFailMutations(t *testing.T) {
+	primary := getTestBackend("primary")
+	s := getTestServer(primary)
+	s.changelog = true
+	ctx := context.Background()
+
+	// A sequence that can't be read means no event can be numbered
+	primary.Write(ctx, &pb.WriteRequest{Key: changelogSequence, Value: &anypb.Any{Value: []byte("garbage")}})
+	if _, err := s.Write(ctx, &pb.WriteRequest{Key: "things/one", Value: &anypb.Any{Value: []byte("hello")}}); err != nil {
+		t.Errorf("Write failed for want of a changelog event: %v", err)
+	}
+	if _, err := s.Delete(ctx, &pb.DeleteRequest{Key: "things/one"}); err != nil {
+		t.Errorf("Delete failed for want of a changelog event: %v", err)
+	}
+	if _, err := s.Count(ctx, &pb.CountRequest{Counter: "hits"}); err != nil {
+		t.Errorf("Count failed for want of a changelog event: %v", err)
+	}
+
+	if events, err := changes(s, &pb.ChangesRequest{}); status.Code(err) != codes.DataLoss {
+		t.Errorf("Lost events went unnoticed: %v, %v", events, err)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"flag"
+	"hash/crc32"
+	"log"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	requireChecksums = flag.Bool("require_checksums", false, "Reject values stored without a checksum, set once every legacy value has been rewritten")
+)
+
+var (
+	corruptionCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_corruption_count",
+	}, []string{"client"})
+	unchecksummedCount = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pstore_unchecksummed_values",
+	})
+)
+
+// Checksummed values start with this header followed by a four byte CRC32C of the
+// key and the remaining bytes. This is the outermost layer of a stored value.
+var checksumHeader = []byte{0x00, 'P', 'C'}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+func checksum(key string, value []byte) uint32 {
+	sum := crc32.Update(0, castagnoli, []byte(key))
+	return crc32.Update(sum, castagnoli, value)
+}
+
+func addChecksum(key string, value []byte) []byte {
+	out := make([]byte, len(checksumHeader)+4, len(checksumHeader)+4+len(value))
+	copy(out, checksumHeader)
+	binary.BigEndian.PutUint32(out[len(checksumHeader):], checksum(key, value))
+	return append(out, value...)
+}
+
+// verifyChecksum strips the checksum from a stored value, values written before
+// checksums were introduced are returned unchanged unless checksums are required.
+// Damage to the header itself looks like a legacy value, hence the metric.
+func verifyChecksum(key string, value []byte) ([]byte, error) {
+	if !bytes.HasPrefix(value, checksumHeader) {
+		unchecksummedCount.Inc()
+		if *requireChecksums {
+			return nil, status.Errorf(codes.DataLoss, "%v has no checksum", key)
+		}
+		return value, nil
+	}
+	if len(value) < len(checksumHeader)+4 {
+		return nil, status.Errorf(codes.DataLoss, "checksum on %v is truncated", key)
+	}
+
+	expected := binary.BigEndian.Uint32(value[len(checksumHeader):])
+	body := value[len(checksumHeader)+4:]
+	if got := checksum(key, body); got != expected {
+		return nil, status.Errorf(codes.DataLoss, "checksum mismatch on %v: %x vs %x", key, got, expected)
+	}
+	return body, nil
+}
+
+// healthyRead is called when the primary returned a corrupt value for a key, it finds a
+// replica with a good copy, queues the primary for repair and returns the good copy
+func (s *Server) healthyRead(ctx context.Context, req *pb.ReadRequest, cerr error) (*pb.ReadResponse, error) {
+	log.Printf("Corrupt value for %v on %v: %v", req.GetKey(), s.clients[0].Name(), cerr)
+	corruptionCount.With(prometheus.Labels{"client": s.clients[0].Name()}).Inc()
+
+	for _, c := range s.clients[1:] {
+		resp, err := s.runRead(ctx, c, req)
+		if err != nil {
+			continue
+		}
+		if _, err := verifyChecksum(req.GetKey(), resp.GetValue().GetValue()); err != nil {
+			corruptionCount.With(prometheus.Labels{"client": c.Name()}).Inc()
+			continue
+		}
+
+		s.wq <- &WriteElement{
+			key:   req.GetKey(),
+			value: resp.GetValue(),
+			cname: s.clients[0].Name(),
+		}
+		return resp, nil
+	}
+
+	return nil, status.Errorf(codes.DataLoss, "no healthy copy of %v: %v", req.GetKey(), cerr)
+}
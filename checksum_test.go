@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestCorruptPrimaryIsRepaired(t *testing.T) {
+	primary := getTestBackend("primary")
+	secondary := getTestBackend("secondary")
+	s := getTestServer(primary, secondary)
+
+	_, err := s.Write(context.Background(), &pb.WriteRequest{Key: "key", Value: &anypb.Any{Value: []byte("value")}})
+	if err != nil {
+		t.Fatalf("Bad write: %v", err)
+	}
+
+	// Flip a bit in the primary copy
+	raw, _ := primary.Read(context.Background(), &pb.ReadRequest{Key: "key"})
+	bad := append([]byte{}, raw.GetValue().GetValue()...)
+	bad[len(bad)-1] ^= 1
+	primary.Write(context.Background(), &pb.WriteRequest{Key: "key", Value: &anypb.Any{Value: bad}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := s.Read(ctx, &pb.ReadRequest{Key: "key"})
+	if err != nil || string(resp.GetValue().GetValue()) != "value" {
+		t.Fatalf("Should have been served from the secondary: %v, %v", resp, err)
+	}
+
+	if len(s.wq) == 0 {
+		t.Fatalf("Primary was not queued for repair")
+	}
+	s.runElem(<-s.wq)
+	raw, _ = primary.Read(context.Background(), &pb.ReadRequest{Key: "key"})
+	if _, err := verifyChecksum("key", raw.GetValue().GetValue()); err != nil {
+		t.Errorf("Primary was not repaired: %v", err)
+	}
+}
+
+func TestChecksumCoversKey(t *testing.T) {
+	stored := addChecksum("one", []byte("value"))
+	if _, err := verifyChecksum("one", stored); err != nil {
+		t.Errorf("Bad verify: %v", err)
+	}
+	if _, err := verifyChecksum("two", stored); err == nil {
+		t.Errorf("Value under the wrong key should fail verification")
+	}
+	if val, err := verifyChecksum("old", []byte("legacy")); err != nil || string(val) != "legacy" {
+		t.Errorf("Values without checksums should pass through: %v, %v", val, err)
+	}
+}
+
+func TestRequireChecksums(t *testing.T) {
+	*requireChecksums = true
+	defer func() { *requireChecksums = false }()
+
+	damaged := addChecksum("one", []byte("value"))
+	damaged[1] = 'X'
+	if _, err := verifyChecksum("one", damaged); err == nil {
+		t.Errorf("Value with a damaged header was accepted")
+	}
+	if _, err := verifyChecksum("one", addChecksum("one", []byte("value"))); err != nil {
+		t.Errorf("Bad verify: %v", err)
+	}
+}
@@ -3,10 +3,13 @@ package pstore_client
 import (
 	"context"
 	"fmt"
+	"io"
 
 	pb "github.com/brotherlogic/pstore/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 )
 
 type PStoreClient interface {
@@ -15,6 +18,33 @@ type PStoreClient interface {
 	GetKeys(ctx context.Context, req *pb.GetKeysRequest) (*pb.GetKeysResponse, error)
 	Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error)
 	Count(ctx context.Context, req *pb.CountRequest) (*pb.CountResponse, error)
+	GetCount(ctx context.Context, req *pb.GetCountRequest) (*pb.GetCountResponse, error)
+	ResetCount(ctx context.Context, req *pb.ResetCountRequest) (*pb.ResetCountResponse, error)
+	AllocateIDs(ctx context.Context, req *pb.AllocateIDsRequest) (*pb.AllocateIDsResponse, error)
+	RegisterDescriptors(ctx context.Context, req *pb.RegisterDescriptorsRequest) (*pb.RegisterDescriptorsResponse, error)
+	QueryIndex(ctx context.Context, req *pb.QueryIndexRequest) (*pb.QueryIndexResponse, error)
+
+	// Scan collects every matching value, use the raw stub to stream large scans
+	Scan(ctx context.Context, req *pb.ScanRequest) ([]*pb.ScanResponse, error)
+	Aggregate(ctx context.Context, req *pb.AggregateRequest) (*pb.AggregateResponse, error)
+	QueryAudit(ctx context.Context, req *pb.QueryAuditRequest) (*pb.QueryAuditResponse, error)
+
+	// Changes calls fn with each changelog event until the stream ends, fn or ctx stop a followed stream
+	Changes(ctx context.Context, req *pb.ChangesRequest, fn func(*pb.ChangeEvent) error) error
+
+	// Export writes an archive of the keys under the prefix to w
+	Export(ctx context.Context, req *pb.ExportRequest, w io.Writer) error
+
+	// Import restores an archive read from r, options are taken from req
+	Import(ctx context.Context, req *pb.ImportRequest, r io.Reader) (*pb.ImportResponse, error)
+	Restore(ctx context.Context, req *pb.RestoreRequest) (*pb.RestoreResponse, error)
+	ListTrash(ctx context.Context, req *pb.ListTrashRequest) (*pb.ListTrashResponse, error)
+	Undelete(ctx context.Context, req *pb.UndeleteRequest) (*pb.UndeleteResponse, error)
+}
+
+// WithNamespace scopes calls made with the returned context to a namespace
+func WithNamespace(ctx context.Context, namespace string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "pstore-namespace", namespace)
 }
 
 type pClient struct {
@@ -22,9 +52,30 @@ type pClient struct {
 }
 
 func GetClient() (PStoreClient, error) {
-	conn, err := grpc.Dial("pstore.pstore:8080",
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(2000*1024*1024)))
+	return GetClientWithCredentials(insecure.NewCredentials(), "")
+}
+
+// bearerToken sends a token with every call, only over a secure transport
+type bearerToken string
+
+func (b bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(b)}, nil
+}
+
+func (b bearerToken) RequireTransportSecurity() bool {
+	return true
+}
+
+// GetClientWithCredentials dials pstore over the given transport, sending token if it is set
+func GetClientWithCredentials(creds credentials.TransportCredentials, token string) (PStoreClient, error) {
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(2000 * 1024 * 1024)),
+	}
+	if token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(bearerToken(token)))
+	}
+	conn, err := grpc.Dial("pstore.pstore:8080", opts...)
 	if err != nil {
 		return nil, fmt.Errorf("dial error on %v -> %w", "pstore.pstore:8080", err)
 	}
@@ -50,3 +101,129 @@ func (c *pClient) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.Delete
 func (c *pClient) Count(ctx context.Context, req *pb.CountRequest) (*pb.CountResponse, error) {
 	return c.pClient.Count(ctx, req)
 }
+
+func (c *pClient) GetCount(ctx context.Context, req *pb.GetCountRequest) (*pb.GetCountResponse, error) {
+	return c.pClient.GetCount(ctx, req)
+}
+
+func (c *pClient) ResetCount(ctx context.Context, req *pb.ResetCountRequest) (*pb.ResetCountResponse, error) {
+	return c.pClient.ResetCount(ctx, req)
+}
+
+func (c *pClient) AllocateIDs(ctx context.Context, req *pb.AllocateIDsRequest) (*pb.AllocateIDsResponse, error) {
+	return c.pClient.AllocateIDs(ctx, req)
+}
+
+func (c *pClient) RegisterDescriptors(ctx context.Context, req *pb.RegisterDescriptorsRequest) (*pb.RegisterDescriptorsResponse, error) {
+	return c.pClient.RegisterDescriptors(ctx, req)
+}
+
+func (c *pClient) QueryIndex(ctx context.Context, req *pb.QueryIndexRequest) (*pb.QueryIndexResponse, error) {
+	return c.pClient.QueryIndex(ctx, req)
+}
+
+func (c *pClient) Scan(ctx context.Context, req *pb.ScanRequest) ([]*pb.ScanResponse, error) {
+	stream, err := c.pClient.Scan(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*pb.ScanResponse
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, resp)
+	}
+}
+
+func (c *pClient) Aggregate(ctx context.Context, req *pb.AggregateRequest) (*pb.AggregateResponse, error) {
+	return c.pClient.Aggregate(ctx, req)
+}
+
+func (c *pClient) QueryAudit(ctx context.Context, req *pb.QueryAuditRequest) (*pb.QueryAuditResponse, error) {
+	return c.pClient.QueryAudit(ctx, req)
+}
+
+func (c *pClient) Restore(ctx context.Context, req *pb.RestoreRequest) (*pb.RestoreResponse, error) {
+	return c.pClient.Restore(ctx, req)
+}
+
+func (c *pClient) ListTrash(ctx context.Context, req *pb.ListTrashRequest) (*pb.ListTrashResponse, error) {
+	return c.pClient.ListTrash(ctx, req)
+}
+
+func (c *pClient) Undelete(ctx context.Context, req *pb.UndeleteRequest) (*pb.UndeleteResponse, error) {
+	return c.pClient.Undelete(ctx, req)
+}
+
+func (c *pClient) Changes(ctx context.Context, req *pb.ChangesRequest, fn func(*pb.ChangeEvent) error) error {
+	stream, err := c.pClient.Changes(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *pClient) Export(ctx context.Context, req *pb.ExportRequest, w io.Writer) error {
+	stream, err := c.pClient.Export(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(chunk.GetData()); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *pClient) Import(ctx context.Context, req *pb.ImportRequest, r io.Reader) (*pb.ImportResponse, error) {
+	stream, err := c.pClient.Import(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &pb.ImportRequest{Existing: req.GetExisting(), DryRun: req.GetDryRun()}
+	if err := stream.Send(options); err != nil {
+		return nil, err
+	}
+	buffer := make([]byte, 1024*1024)
+	for {
+		n, err := r.Read(buffer)
+		if n > 0 {
+			if serr := stream.Send(&pb.ImportRequest{Data: append([]byte{}, buffer[:n]...)}); serr != nil {
+				return nil, serr
+			}
+		}
+		if err == io.EOF {
+			return stream.CloseAndRecv()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package pstore_client
+
+import (
+	"context"
+	"sync"
+
+	pb "github.com/brotherlogic/pstore/proto"
+)
+
+// IDAllocator hands out IDs from a named sequence, leasing them from the
+// server a block at a time. IDs left in a block when the process exits are
+// never reissued, so sequences can have gaps but never repeats.
+type IDAllocator struct {
+	client    PStoreClient
+	sequence  string
+	blockSize int64
+
+	lock sync.Mutex
+	next int64
+	last int64
+}
+
+func NewIDAllocator(client PStoreClient, sequence string, blockSize int64) *IDAllocator {
+	if blockSize <= 0 {
+		blockSize = 1
+	}
+	return &IDAllocator{client: client, sequence: sequence, blockSize: blockSize}
+}
+
+// Next returns the next ID, only calling the server when the current block is used up
+func (a *IDAllocator) Next(ctx context.Context) (int64, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.next == 0 || a.next > a.last {
+		resp, err := a.client.AllocateIDs(ctx, &pb.AllocateIDsRequest{
+			Sequence: a.sequence,
+			Count:    a.blockSize,
+		})
+		if err != nil {
+			return 0, err
+		}
+		a.next = resp.GetFirst()
+		a.last = resp.GetLast()
+	}
+
+	id := a.next
+	a.next++
+	return id, nil
+}
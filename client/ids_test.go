@@ -0,0 +1,31 @@
+package pstore_client
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIDAllocator(t *testing.T) {
+	client := GetTestClient()
+	first := NewIDAllocator(client, "ids", 10)
+	second := NewIDAllocator(client, "ids", 10)
+
+	seen := make(map[int64]bool)
+	for i := 0; i < 25; i++ {
+		for _, a := range []*IDAllocator{first, second} {
+			id, err := a.Next(context.Background())
+			if err != nil {
+				t.Fatalf("Bad allocation: %v", err)
+			}
+			if seen[id] {
+				t.Fatalf("ID %v was issued twice", id)
+			}
+			seen[id] = true
+		}
+	}
+
+	// 50 ids from blocks of 10 for two allocators should have needed six leases
+	if client.(*TestClient).sequences["ids"] != 60 {
+		t.Errorf("Wrong number of leases: %v", client.(*TestClient).sequences["ids"])
+	}
+}
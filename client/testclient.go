@@ -2,37 +2,49 @@ package pstore_client
 
 import (
 	"context"
+	"io"
 	"strings"
+	"sync"
 
 	pb "github.com/brotherlogic/pstore/proto"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
 	"google.golang.org/protobuf/types/known/anypb"
 )
 
 type TestClient struct {
-	mapper  map[string][]byte
-	counter int64
+	lock      sync.Mutex
+	mapper    map[string]*anypb.Any
+	counters  map[string]int64
+	sequences map[string]int64
 }
 
 func GetTestClient() PStoreClient {
-	return &TestClient{mapper: make(map[string][]byte)}
+	return &TestClient{mapper: make(map[string]*anypb.Any), counters: make(map[string]int64), sequences: make(map[string]int64)}
 }
 
 func (c *TestClient) Read(ctx context.Context, req *pb.ReadRequest) (*pb.ReadResponse, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	if val, ok := c.mapper[req.GetKey()]; ok {
-		return &pb.ReadResponse{Value: &anypb.Any{Value: val}}, nil
+		return &pb.ReadResponse{Value: proto.Clone(val).(*anypb.Any)}, nil
 	}
 
 	return nil, status.Errorf(codes.NotFound, "Unable to locate %v", req.GetKey())
 }
 
 func (c *TestClient) Write(ctx context.Context, req *pb.WriteRequest) (*pb.WriteResponse, error) {
-	c.mapper[req.Key] = req.GetValue().Value
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.mapper[req.Key] = proto.Clone(req.GetValue()).(*anypb.Any)
 	return &pb.WriteResponse{}, nil
 }
 
 func (c *TestClient) GetKeys(ctx context.Context, req *pb.GetKeysRequest) (*pb.GetKeysResponse, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	var keys []string
 	for key := range c.mapper {
 		if strings.HasPrefix(key, req.GetPrefix()) {
@@ -51,12 +63,105 @@ func (c *TestClient) GetKeys(ctx context.Context, req *pb.GetKeysRequest) (*pb.G
 }
 
 func (c *TestClient) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	delete(c.mapper, req.GetKey())
 	return &pb.DeleteResponse{}, nil
 }
 
 func (c *TestClient) Count(ctx context.Context, req *pb.CountRequest) (*pb.CountResponse, error) {
-	val := c.counter
-	c.counter++
-	return &pb.CountResponse{Count: val}, nil
+	if req.GetDelta() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "counters cannot be decremented (%v)", req.GetDelta())
+	}
+	delta := req.GetDelta()
+	if delta == 0 {
+		delta = 1
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.counters[req.GetCounter()] += delta
+	return &pb.CountResponse{Count: c.counters[req.GetCounter()]}, nil
+}
+
+func (c *TestClient) GetCount(ctx context.Context, req *pb.GetCountRequest) (*pb.GetCountResponse, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return &pb.GetCountResponse{Count: c.counters[req.GetCounter()]}, nil
+}
+
+func (c *TestClient) ResetCount(ctx context.Context, req *pb.ResetCountRequest) (*pb.ResetCountResponse, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.counters, req.GetCounter())
+	return &pb.ResetCountResponse{}, nil
+}
+
+func (c *TestClient) AllocateIDs(ctx context.Context, req *pb.AllocateIDsRequest) (*pb.AllocateIDsResponse, error) {
+	if req.GetCount() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "cannot allocate %v ids", req.GetCount())
+	}
+	count := req.GetCount()
+	if count == 0 {
+		count = 1
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	first := c.sequences[req.GetSequence()] + 1
+	c.sequences[req.GetSequence()] += count
+	return &pb.AllocateIDsResponse{First: first, Last: c.sequences[req.GetSequence()]}, nil
+}
+
+func (c *TestClient) RegisterDescriptors(ctx context.Context, req *pb.RegisterDescriptorsRequest) (*pb.RegisterDescriptorsResponse, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(req.GetDescriptorSet(), set); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "bad descriptor set: %v", err)
+	}
+
+	resp := &pb.RegisterDescriptorsResponse{}
+	for _, file := range set.GetFile() {
+		for _, msg := range file.GetMessageType() {
+			resp.Messages = append(resp.Messages, file.GetPackage()+"."+msg.GetName())
+		}
+	}
+	return resp, nil
+}
+
+func (c *TestClient) QueryIndex(ctx context.Context, req *pb.QueryIndexRequest) (*pb.QueryIndexResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "the test client does not support indexes")
+}
+
+func (c *TestClient) Scan(ctx context.Context, req *pb.ScanRequest) ([]*pb.ScanResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "the test client does not support scans")
+}
+
+func (c *TestClient) Aggregate(ctx context.Context, req *pb.AggregateRequest) (*pb.AggregateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "the test client does not support aggregations")
+}
+
+func (c *TestClient) QueryAudit(ctx context.Context, req *pb.QueryAuditRequest) (*pb.QueryAuditResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "the test client does not keep an audit log")
+}
+
+func (c *TestClient) Export(ctx context.Context, req *pb.ExportRequest, w io.Writer) error {
+	return status.Errorf(codes.Unimplemented, "the test client does not support exports")
+}
+
+func (c *TestClient) Import(ctx context.Context, req *pb.ImportRequest, r io.Reader) (*pb.ImportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "the test client does not support imports")
+}
+
+func (c *TestClient) Restore(ctx context.Context, req *pb.RestoreRequest) (*pb.RestoreResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "the test client does not support restores")
+}
+
+func (c *TestClient) ListTrash(ctx context.Context, req *pb.ListTrashRequest) (*pb.ListTrashResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "the test client does not keep a trash")
+}
+
+func (c *TestClient) Undelete(ctx context.Context, req *pb.UndeleteRequest) (*pb.UndeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "the test client does not keep a trash")
+}
+
+func (c *TestClient) Changes(ctx context.Context, req *pb.ChangesRequest, fn func(*pb.ChangeEvent) error) error {
+	return status.Errorf(codes.Unimplemented, "the test client does not keep a changelog")
 }
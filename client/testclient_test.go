@@ -31,6 +31,14 @@ func TestTestClient(t *testing.T) {
 		t.Errorf("Bad val: %v", val)
 	}
 
+	typed, _ := anypb.New(&pb.ReadRequest{Key: "typed"})
+	client.Write(context.Background(), &pb.WriteRequest{Key: "typed", Value: typed})
+	val, err = client.Read(context.Background(), &pb.ReadRequest{Key: "typed"})
+	if err != nil || val.GetValue().GetTypeUrl() != typed.GetTypeUrl() {
+		t.Errorf("Type URL was lost: %v, %v", val, err)
+	}
+	client.Delete(context.Background(), &pb.DeleteRequest{Key: "typed"})
+
 	keys, err := client.GetKeys(context.Background(), &pb.GetKeysRequest{})
 	if err != nil {
 		t.Errorf("Bad get keys: %v", err)
@@ -78,3 +86,30 @@ func TestGetKeysSuffix(t *testing.T) {
 		t.Errorf("Should only be one key: %v", keys)
 	}
 }
+
+func TestCounters(t *testing.T) {
+	client := GetTestClient()
+
+	first, err := client.Count(context.Background(), &pb.CountRequest{Counter: "one"})
+	if err != nil || first.GetCount() != 1 {
+		t.Errorf("Bad first count: %v, %v", first, err)
+	}
+	resp, err := client.Count(context.Background(), &pb.CountRequest{Counter: "one", Delta: 5})
+	if err != nil || resp.GetCount() != 6 {
+		t.Errorf("Bad count: %v, %v", resp, err)
+	}
+
+	other, err := client.GetCount(context.Background(), &pb.GetCountRequest{Counter: "two"})
+	if err != nil || other.GetCount() != 0 {
+		t.Errorf("Counters should be independent: %v, %v", other, err)
+	}
+
+	_, err = client.ResetCount(context.Background(), &pb.ResetCountRequest{Counter: "one"})
+	if err != nil {
+		t.Fatalf("Bad reset: %v", err)
+	}
+	val, err := client.GetCount(context.Background(), &pb.GetCountRequest{Counter: "one"})
+	if err != nil || val.GetCount() != 0 {
+		t.Errorf("Counter was not reset: %v, %v", val, err)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	coalescedCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_coalesced_calls",
+	}, []string{"kind"})
+	coalescedLeaders = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_coalesced_leaders",
+	}, []string{"kind"})
+)
+
+type flight[T proto.Message] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// coalescer collapses concurrent calls with the same id into one, every caller
+// gets its own copy of the result
+type coalescer[T proto.Message] struct {
+	lock    sync.Mutex
+	flights map[string]*flight[T]
+}
+
+// do runs fn once for all the callers asking for id at the same time. fn carries
+// on if the caller that started it goes away, so the others still get an answer.
+func (c *coalescer[T]) do(ctx context.Context, kind, id string, fn func(ctx context.Context) (T, error)) (T, error) {
+	c.lock.Lock()
+	f, ok := c.flights[id]
+	if ok {
+		coalescedCalls.With(prometheus.Labels{"kind": kind}).Inc()
+	} else {
+		if c.flights == nil {
+			c.flights = make(map[string]*flight[T])
+		}
+		f = &flight[T]{done: make(chan struct{})}
+		c.flights[id] = f
+		coalescedLeaders.With(prometheus.Labels{"kind": kind}).Inc()
+
+		deadline, has := ctx.Deadline()
+		timeout := time.Minute
+		if has {
+			timeout = time.Until(deadline)
+		}
+		fctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		go func() {
+			defer cancel()
+			f.value, f.err = fn(fctx)
+			c.lock.Lock()
+			if c.flights[id] == f {
+				delete(c.flights, id)
+			}
+			c.lock.Unlock()
+			close(f.done)
+		}()
+	}
+	c.lock.Unlock()
+
+	var zero T
+	select {
+	case <-f.done:
+		if f.err != nil {
+			return zero, f.err
+		}
+		return proto.Clone(f.value).(T), nil
+	case <-ctx.Done():
+		return zero, status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+// forget stops later callers joining the call in flight for id, as its result may be stale
+func (c *coalescer[T]) forget(id string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.flights, id)
+}
+
+func (c *coalescer[T]) forgetAll() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	clear(c.flights)
+}
+
+// invalidate drops everything read before key was changed, so callers see their own writes
+func (s *Server) invalidate(key string) {
+	s.cache.invalidate(key)
+	s.reads.forget(key)
+	s.listings.forgetAll()
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestCoalesce(t *testing.T) {
+	c := &coalescer[*pb.ReadResponse]{}
+	release := make(chan struct{})
+	calls := atomic.Int32{}
+	fn := func(ctx context.Context) (*pb.ReadResponse, error) {
+		calls.Add(1)
+		<-release
+		return &pb.ReadResponse{Value: &anypb.Any{Value: []byte("hello")}}, nil
+	}
+
+	// The first caller gives up, the rest still get the shared answer
+	leader, cancel := context.WithCancel(context.Background())
+	leaderDone := make(chan error)
+	go func() {
+		_, err := c.do(leader, "read", "things/one", fn)
+		leaderDone <- err
+	}()
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-leaderDone; status.Code(err) != codes.Canceled {
+		t.Errorf("Cancelled caller returned %v", err)
+	}
+
+	results := make([]*pb.ReadResponse, 5)
+	waitgroup := &sync.WaitGroup{}
+	for i := range results {
+		waitgroup.Add(1)
+		go func() {
+			defer waitgroup.Done()
+			results[i], _ = c.do(context.Background(), "read", "things/one", fn)
+		}()
+	}
+	time.Sleep(time.Millisecond * 10)
+	close(release)
+	waitgroup.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("Identical calls were not coalesced: %v calls", calls.Load())
+	}
+	for _, r := range results {
+		if string(r.GetValue().GetValue()) != "hello" {
+			t.Errorf("Bad shared result: %v", r)
+		}
+	}
+	if results[0] == results[1] {
+		t.Errorf("Callers share a result they could change")
+	}
+}
+
+func TestCoalesceForget(t *testing.T) {
+	c := &coalescer[*pb.ReadResponse]{}
+	release := make(chan struct{})
+	calls := atomic.Int32{}
+	fn := func(ctx context.Context) (*pb.ReadResponse, error) {
+		calls.Add(1)
+		<-release
+		return &pb.ReadResponse{}, nil
+	}
+
+	done := make(chan bool)
+	go func() {
+		c.do(context.Background(), "read", "things/one", fn)
+		done <- true
+	}()
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A write lands, so the next read cannot join the one already in flight
+	c.forget("things/one")
+	go func() {
+		c.do(context.Background(), "read", "things/one", fn)
+		done <- true
+	}()
+	for calls.Load() == 1 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	<-done
+	<-done
+}
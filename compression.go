@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	compression          = flag.String("compression", "none", "Compression for values over the threshold (none, gzip or zstd)")
+	compressionThreshold = flag.Int("compression_threshold", 1024, "Values smaller than this many bytes are stored uncompressed")
+	compressionPrefixes  = flag.String("compression_prefixes", "", "Per prefix compression overrides, e.g. recordcollection/=zstd,config/=none")
+)
+
+var (
+	compressionIn = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_compression_bytes_in",
+	}, []string{"algo"})
+	compressionOut = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_compression_bytes_out",
+	}, []string{"algo"})
+	compressionSaved = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_compression_bytes_saved",
+	}, []string{"algo"})
+)
+
+// Compressed values start with this header followed by an algorithm byte. A serialized
+// proto never starts with a zero byte, so stored values without it are left alone.
+var compressionHeader = []byte{0x00, 'P', 'Z'}
+
+const (
+	algoNone byte = iota
+	algoGzip
+	algoZstd
+)
+
+var algoNames = map[string]byte{"none": algoNone, "gzip": algoGzip, "zstd": algoZstd}
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+type compressionConfig struct {
+	algo      byte
+	threshold int
+	prefixes  map[string]byte
+}
+
+func parseCompression(algo string, threshold int, prefixes string) (*compressionConfig, error) {
+	config := &compressionConfig{threshold: threshold, prefixes: make(map[string]byte)}
+
+	a, ok := algoNames[algo]
+	if !ok {
+		return nil, fmt.Errorf("unknown compression %q", algo)
+	}
+	config.algo = a
+
+	for _, entry := range strings.Split(prefixes, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("bad compression prefix %q", entry)
+		}
+		a, ok := algoNames[parts[1]]
+		if !ok {
+			return nil, fmt.Errorf("unknown compression %q for prefix %v", parts[1], parts[0])
+		}
+		config.prefixes[parts[0]] = a
+	}
+
+	return config, nil
+}
+
+// algoFor picks the algorithm for a key, the longest matching prefix wins over the default
+func (c *compressionConfig) algoFor(key string, size int) byte {
+	best := -1
+	algo := algoNone
+	for prefix, a := range c.prefixes {
+		if strings.HasPrefix(key, prefix) && len(prefix) > best {
+			best = len(prefix)
+			algo = a
+		}
+	}
+	if best >= 0 {
+		return algo
+	}
+
+	if size < c.threshold {
+		return algoNone
+	}
+	return c.algo
+}
+
+// compress returns the value to hand to the backends for key
+func (c *compressionConfig) compress(key string, value []byte) ([]byte, error) {
+	if c == nil {
+		return value, nil
+	}
+
+	algo := c.algoFor(key, len(value))
+	if algo == algoNone {
+		return value, nil
+	}
+
+	buf := bytes.NewBuffer(append(append([]byte{}, compressionHeader...), algo))
+	switch algo {
+	case algoGzip:
+		w := gzip.NewWriter(buf)
+		if _, err := w.Write(value); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	case algoZstd:
+		buf.Write(zstdEncoder.EncodeAll(value, nil))
+	}
+
+	name := algoLabel(algo)
+	compressionIn.With(prometheus.Labels{"algo": name}).Add(float64(len(value)))
+	compressionOut.With(prometheus.Labels{"algo": name}).Add(float64(buf.Len()))
+
+	// Not worth it, store as is
+	if buf.Len() >= len(value) {
+		return value, nil
+	}
+	compressionSaved.With(prometheus.Labels{"algo": name}).Add(float64(len(value) - buf.Len()))
+	return buf.Bytes(), nil
+}
+
+// decompress undoes compress, values stored without the header are returned unchanged
+func decompress(value []byte) ([]byte, error) {
+	if len(value) <= len(compressionHeader) || !bytes.HasPrefix(value, compressionHeader) {
+		return value, nil
+	}
+
+	algo := value[len(compressionHeader)]
+	body := value[len(compressionHeader)+1:]
+	switch algo {
+	case algoGzip:
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case algoZstd:
+		return zstdDecoder.DecodeAll(body, nil)
+	}
+
+	return nil, fmt.Errorf("unknown compression algorithm %v", algo)
+}
+
+func algoLabel(algo byte) string {
+	for name, a := range algoNames {
+		if a == algo {
+			return name
+		}
+	}
+	return strconv.Itoa(int(algo))
+}
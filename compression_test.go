@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestCompressionRoundTrip(t *testing.T) {
+	config, err := parseCompression("zstd", 100, "gz/=gzip,small/=none")
+	if err != nil {
+		t.Fatalf("Bad config: %v", err)
+	}
+
+	value := bytes.Repeat([]byte("compressible"), 100)
+	for _, key := range []string{"default", "gz/key", "small/key"} {
+		stored, err := config.compress(key, value)
+		if err != nil {
+			t.Fatalf("Bad compress of %v: %v", key, err)
+		}
+		if key == "small/key" && !bytes.Equal(stored, value) {
+			t.Errorf("%v should not have been compressed", key)
+		}
+		if key != "small/key" && len(stored) >= len(value) {
+			t.Errorf("%v was not compressed: %v", key, len(stored))
+		}
+
+		back, err := decompress(stored)
+		if err != nil || !bytes.Equal(back, value) {
+			t.Errorf("Bad round trip for %v: %v", key, err)
+		}
+	}
+
+	if _, err := parseCompression("lz4", 0, ""); err == nil {
+		t.Errorf("Unknown algorithms should be rejected")
+	}
+}
+
+func TestCompressedValuesCoexist(t *testing.T) {
+	primary := getTestBackend("primary")
+	s := getTestServer(primary)
+
+	// Written before compression was enabled
+	plain := bytes.Repeat([]byte{1, 2, 3}, 1000)
+	s.Write(context.Background(), &pb.WriteRequest{Key: "old", Value: &anypb.Any{Value: plain}})
+
+	s.compression, _ = parseCompression("gzip", 10, "")
+	s.Write(context.Background(), &pb.WriteRequest{Key: "new", Value: &anypb.Any{Value: plain}})
+
+	raw, _ := primary.Read(context.Background(), &pb.ReadRequest{Key: "new"})
+	if len(raw.GetValue().GetValue()) >= len(plain) {
+		t.Errorf("Value was not compressed in the backend")
+	}
+
+	for _, key := range []string{"old", "new"} {
+		resp, err := s.Read(context.Background(), &pb.ReadRequest{Key: key})
+		if err != nil || !bytes.Equal(resp.GetValue().GetValue(), plain) {
+			t.Errorf("Bad read of %v: %v", key, err)
+		}
+	}
+}
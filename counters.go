@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Counters live in the key space under this prefix, one key per counter
+const counterPrefix = reservedPrefix + "counters/"
+
+var (
+	counterOps = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_counter_ops",
+	}, []string{"op", "code"})
+	counterAuthority = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_counter_authority",
+	}, []string{"client"})
+)
+
+func counterKey(name string) string {
+	return counterPrefix + name
+}
+
+// newer reports whether a should replace b when reconciling counter state
+func newer(a, b *pb.CounterState) bool {
+	if a.GetEpoch() != b.GetEpoch() {
+		return a.GetEpoch() > b.GetEpoch()
+	}
+	return a.GetValue() > b.GetValue()
+}
+
+type keyMutex struct {
+	sync.Mutex
+	refs int
+}
+
+// lockKey serialises read-modify-write operations on key within this process. Entries
+// are dropped once nobody holds or waits for them.
+func (s *Server) lockKey(key string) func() {
+	s.keyLock.Lock()
+	if s.keyLocks == nil {
+		s.keyLocks = make(map[string]*keyMutex)
+	}
+	l, ok := s.keyLocks[key]
+	if !ok {
+		l = &keyMutex{}
+		s.keyLocks[key] = l
+	}
+	l.refs++
+	s.keyLock.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		s.keyLock.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(s.keyLocks, key)
+		}
+		s.keyLock.Unlock()
+	}
+}
+
+// loadCounter reads the counter stored at key from every backend and returns the most
+// recent state, backends which are behind are queued for repair. If the counter has never
+// been stored it is seeded from the native counter on the primary, unless native is empty.
+// missing names the backends that did not answer. Must hold the key lock.
+func (s *Server) loadCounter(ctx context.Context, key, native string) (state *pb.CounterState, missing []string, err error) {
+	type result struct {
+		client pstore
+		state  *pb.CounterState
+		err    error
+	}
+	results := make([]result, len(s.clients))
+	waitgroup := &sync.WaitGroup{}
+	for i, c := range s.clients {
+		waitgroup.Add(1)
+		go func() {
+			defer waitgroup.Done()
+			results[i].client = c
+			resp, err := s.runRead(ctx, c, &pb.ReadRequest{Key: key})
+			if err != nil {
+				results[i].err = err
+				return
+			}
+			state := &pb.CounterState{}
+			results[i].err = proto.Unmarshal(resp.GetValue().GetValue(), state)
+			results[i].state = state
+		}()
+	}
+	waitgroup.Wait()
+
+	var best *pb.CounterState
+	for _, r := range results {
+		if r.err != nil && status.Code(r.err) != codes.NotFound {
+			missing = append(missing, r.client.Name())
+		}
+		if r.err == nil && (best == nil || newer(r.state, best)) {
+			best = r.state
+		}
+	}
+	if len(missing) == len(results) {
+		return nil, missing, status.Errorf(codes.Unavailable, "no backend could read counter %v: %v", key, results[0].err)
+	}
+
+	// Never hand out anything below what this process has already seen
+	s.keyLock.Lock()
+	mark, ok := s.counterMarks[key]
+	s.keyLock.Unlock()
+	if ok && (best == nil || newer(mark, best)) {
+		best = mark
+	}
+
+	if best == nil {
+		if native == "" {
+			return &pb.CounterState{}, missing, nil
+		}
+
+		// Counters created before the counter subsystem only exist natively in the primary.
+		// Backends can only read a native counter by counting it, and like Redis INCR they
+		// return the value after the increment. That value was never handed out, so the
+		// seed is the one before it, and it is stored straight away so seeding happens once.
+		resp, err := s.runCount(ctx, s.clients[0], &pb.CountRequest{Counter: native})
+		if err != nil {
+			return nil, missing, status.Errorf(status.Code(err), "unable to seed counter %v from %v: %v", native, s.clients[0].Name(), err)
+		}
+		state := &pb.CounterState{Value: resp.GetCount() - 1}
+		if err := s.storeCounter(ctx, key, state, nil); err != nil {
+			return nil, missing, err
+		}
+		return state, missing, nil
+	}
+
+	for _, r := range results {
+		if (r.err == nil && newer(best, r.state)) || status.Code(r.err) == codes.NotFound {
+			log.Printf("Counter %v is behind on %v, repairing", key, r.client.Name())
+			cCountDiffs.Inc()
+			data, err := anypb.New(best)
+			if err == nil {
+				s.wq <- &WriteElement{
+					key:   key,
+					value: data,
+					cname: r.client.Name(),
+				}
+			}
+		}
+	}
+
+	return proto.Clone(best).(*pb.CounterState), missing, nil
+}
+
+// counterOrder lists the backends in the order a counter is written, its authority first
+func (s *Server) counterOrder(state *pb.CounterState) []pstore {
+	order := []pstore{}
+	for _, c := range s.clients {
+		if c.Name() == state.GetAuthority() {
+			order = append(order, c)
+		}
+	}
+	for _, c := range s.clients {
+		if c.Name() != state.GetAuthority() {
+			order = append(order, c)
+		}
+	}
+	return order
+}
+
+// storeCounter writes the counter to its authority, new counters start with the primary,
+// and then to all the others. If the authority cannot be written the first backend that
+// accepts the write takes over, and stays the authority from then on. Must hold the key lock.
+func (s *Server) storeCounter(ctx context.Context, key string, state *pb.CounterState, rec *auditRecord) error {
+	order := s.counterOrder(state)
+	previous := state.GetAuthority()
+
+	var req *pb.WriteRequest
+	var err error
+	authority := -1
+	for i, c := range order {
+		state.Authority = c.Name()
+		value, merr := anypb.New(state)
+		if merr != nil {
+			return merr
+		}
+		req = &pb.WriteRequest{Key: key, Value: value}
+		_, err = s.runWrite(ctx, c, req)
+		rec.backend(c.Name(), err)
+		if err == nil {
+			authority = i
+			break
+		}
+	}
+	if authority < 0 {
+		state.Authority = previous
+		return status.Errorf(codes.Unavailable, "no backend could store counter %v: %v", key, err)
+	}
+	if previous != "" && previous != state.GetAuthority() {
+		log.Printf("Counter %v could not be written to %v, %v is now its authority", key, previous, state.GetAuthority())
+	}
+	counterAuthority.With(prometheus.Labels{"client": state.GetAuthority()}).Inc()
+	s.keyLock.Lock()
+	if s.counterMarks == nil {
+		s.counterMarks = make(map[string]*pb.CounterState)
+	}
+	s.counterMarks[key] = proto.Clone(state).(*pb.CounterState)
+	s.keyLock.Unlock()
+
+	waitgroup := &sync.WaitGroup{}
+	for _, c := range order[authority+1:] {
+		waitgroup.Add(1)
+		rec.expect()
+		go func() {
+			_, werr := s.runWrite(ctx, c, req)
+			rec.answer(c.Name(), werr)
+			waitgroup.Done()
+		}()
+	}
+	waitgroup.Wait()
+
+	s.recordChange(ctx, pb.ChangeEvent_COUNTER, key, req.GetValue())
+	return nil
+}
+
+func (s *Server) Count(ctx context.Context, req *pb.CountRequest) (*pb.CountResponse, error) {
+	ns, err := s.namespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ns != "" && req.GetCounter() != "" {
+		req = &pb.CountRequest{Counter: namespacedKey(ns, req.GetCounter()), Delta: req.GetDelta()}
+	}
+	rec := s.newAudit(ctx, "count", counterKey(req.GetCounter()), nil)
+	var resp *pb.CountResponse
+	err = s.authorize(ctx, rightWrite, counterKey(req.GetCounter()))
+	if err == nil {
+		resp, err = s.count(ctx, req, rec)
+	}
+	s.finishAudit(rec, err)
+	counterOps.With(prometheus.Labels{"op": "count", "code": fmt.Sprintf("%v", status.Code(err))}).Inc()
+	return resp, err
+}
+
+func (s *Server) count(ctx context.Context, req *pb.CountRequest, rec *auditRecord) (*pb.CountResponse, error) {
+	if req.GetCounter() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "counter name must be set")
+	}
+	delta := req.GetDelta()
+	if delta < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "counters cannot be decremented (%v)", delta)
+	}
+	if delta == 0 {
+		delta = 1
+	}
+	rec.setDelta(delta)
+
+	key := counterKey(req.GetCounter())
+	defer s.lockKey(key)()
+	state, _, err := s.loadCounter(ctx, key, req.GetCounter())
+	if err != nil {
+		return nil, err
+	}
+
+	state.Value += delta
+	if err := s.storeCounter(ctx, key, state, rec); err != nil {
+		return nil, err
+	}
+
+	return &pb.CountResponse{Count: state.GetValue()}, nil
+}
+
+func (s *Server) GetCount(ctx context.Context, req *pb.GetCountRequest) (*pb.GetCountResponse, error) {
+	if req.GetCounter() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "counter name must be set")
+	}
+	ns, err := s.namespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := counterKey(namespacedKey(ns, req.GetCounter()))
+	if err := s.authorize(ctx, rightRead, key); err != nil {
+		return nil, err
+	}
+	defer s.lockKey(key)()
+	state, _, err := s.loadCounter(ctx, key, namespacedKey(ns, req.GetCounter()))
+	counterOps.With(prometheus.Labels{"op": "get", "code": fmt.Sprintf("%v", status.Code(err))}).Inc()
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetCountResponse{Count: state.GetValue()}, nil
+}
+
+func (s *Server) ResetCount(ctx context.Context, req *pb.ResetCountRequest) (*pb.ResetCountResponse, error) {
+	if req.GetCounter() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "counter name must be set")
+	}
+	ns, err := s.namespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := counterKey(namespacedKey(ns, req.GetCounter()))
+	rec := s.newAudit(ctx, "reset_count", key, nil)
+	err = s.authorize(ctx, rightWrite, key)
+	if err == nil {
+		defer s.lockKey(key)()
+		var state *pb.CounterState
+		state, _, err = s.loadCounter(ctx, key, "")
+		if err == nil {
+			err = s.storeCounter(ctx, key, &pb.CounterState{Epoch: state.GetEpoch() + 1, Authority: state.GetAuthority()}, rec)
+		}
+	}
+	s.finishAudit(rec, err)
+	counterOps.With(prometheus.Labels{"op": "reset", "code": fmt.Sprintf("%v", status.Code(err))}).Inc()
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.ResetCountResponse{}, nil
+}
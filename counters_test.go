@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestCountIsNamed(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"), getTestBackend("secondary"))
+
+	// New counters are seeded from the native counter on the primary, which starts at zero
+	for i := 0; i < 3; i++ {
+		s.Count(context.Background(), &pb.CountRequest{Counter: "one"})
+	}
+	resp, err := s.Count(context.Background(), &pb.CountRequest{Counter: "two", Delta: 10})
+	if err != nil {
+		t.Fatalf("Bad count: %v", err)
+	}
+	if resp.GetCount() != 10 {
+		t.Errorf("Counter two should have been seeded independently: %v", resp)
+	}
+
+	val, err := s.GetCount(context.Background(), &pb.GetCountRequest{Counter: "one"})
+	if err != nil || val.GetCount() != 3 {
+		t.Errorf("Bad get: %v, %v", val, err)
+	}
+
+	// Getting should not have moved the counter
+	val, err = s.GetCount(context.Background(), &pb.GetCountRequest{Counter: "one"})
+	if err != nil || val.GetCount() != 3 {
+		t.Errorf("Bad second get: %v, %v", val, err)
+	}
+}
+
+func TestCountSurvivesFailover(t *testing.T) {
+	primary := getTestBackend("primary")
+	secondary := getTestBackend("secondary")
+	s := getTestServer(primary, secondary)
+
+	for i := 0; i < 5; i++ {
+		s.Count(context.Background(), &pb.CountRequest{Counter: "ids"})
+	}
+	resp, err := s.GetCount(context.Background(), &pb.GetCountRequest{Counter: "ids"})
+	if err != nil {
+		t.Fatalf("Bad get: %v", err)
+	}
+	last := resp.GetCount()
+
+	primary.down = true
+	cresp, err := s.Count(context.Background(), &pb.CountRequest{Counter: "ids"})
+	if err != nil || cresp.GetCount() != last+1 {
+		t.Fatalf("Bad count on failover: %v, %v", cresp, err)
+	}
+
+	// Primary comes back with a stale value, we should not go backwards
+	primary.down = false
+	cresp, err = s.Count(context.Background(), &pb.CountRequest{Counter: "ids"})
+	if err != nil || cresp.GetCount() != last+2 {
+		t.Errorf("Counter went backwards: %v, %v", cresp, err)
+	}
+	if len(s.wq) != 1 {
+		t.Errorf("Stale primary should have been queued for repair: %v", len(s.wq))
+	}
+}
+
+func TestCountAuthorityIsSticky(t *testing.T) {
+	primary := getTestBackend("primary")
+	secondary := getTestBackend("secondary")
+	s := getTestServer(primary, secondary)
+	ctx := context.Background()
+
+	s.Count(ctx, &pb.CountRequest{Counter: "ids"})
+	if authority := counterState(t, secondary, "ids").GetAuthority(); authority != "primary" {
+		t.Fatalf("New counter should start with the primary as its authority: %v", authority)
+	}
+
+	primary.down = true
+	s.Count(ctx, &pb.CountRequest{Counter: "ids"})
+	primary.down = false
+
+	// The primary is back but the counter stays with the backend that took over
+	s.Count(ctx, &pb.CountRequest{Counter: "ids"})
+	if authority := counterState(t, primary, "ids").GetAuthority(); authority != "secondary" {
+		t.Errorf("Authority moved back without a failure: %v", authority)
+	}
+}
+
+func counterState(t *testing.T, backend *testBackend, name string) *pb.CounterState {
+	resp, err := backend.Read(context.Background(), &pb.ReadRequest{Key: counterKey(name)})
+	if err != nil {
+		t.Fatalf("Unable to read counter %v from %v: %v", name, backend.Name(), err)
+	}
+	state := &pb.CounterState{}
+	if err := proto.Unmarshal(resp.GetValue().GetValue(), state); err != nil {
+		t.Fatalf("Bad counter state: %v", err)
+	}
+	return state
+}
+
+func TestResetCount(t *testing.T) {
+	primary := getTestBackend("primary")
+	secondary := getTestBackend("secondary")
+	s := getTestServer(primary, secondary)
+
+	s.Count(context.Background(), &pb.CountRequest{Counter: "reset", Delta: 20})
+
+	secondary.down = true
+	_, err := s.ResetCount(context.Background(), &pb.ResetCountRequest{Counter: "reset"})
+	if err != nil {
+		t.Fatalf("Bad reset: %v", err)
+	}
+	secondary.down = false
+
+	// The secondary still holds the old value but the reset should win
+	resp, err := s.Count(context.Background(), &pb.CountRequest{Counter: "reset"})
+	if err != nil || resp.GetCount() != 1 {
+		t.Errorf("Reset was lost: %v, %v", resp, err)
+	}
+}
+
+func TestCountSeedsFromNative(t *testing.T) {
+	primary := getTestBackend("primary")
+	s := getTestServer(primary)
+	for i := 0; i < 5; i++ {
+		primary.client.Count(context.Background(), &pb.CountRequest{Counter: "legacy"})
+	}
+
+	// Getting a counter which only exists natively returns the last value it handed out,
+	// and the next count carries on from there without repeating or skipping a value
+	val, err := s.GetCount(context.Background(), &pb.GetCountRequest{Counter: "legacy"})
+	if err != nil || val.GetCount() != 5 {
+		t.Fatalf("Bad get: %v, %v", val, err)
+	}
+	resp, err := s.Count(context.Background(), &pb.CountRequest{Counter: "legacy"})
+	if err != nil || resp.GetCount() != 6 {
+		t.Errorf("Count after get: %v, %v", resp, err)
+	}
+	native, err := primary.client.GetCount(context.Background(), &pb.GetCountRequest{Counter: "legacy"})
+	if err != nil || native.GetCount() != 6 {
+		t.Errorf("Seeding should count the native counter once: %v, %v", native, err)
+	}
+
+	primary.down = true
+	if _, err := s.Count(context.Background(), &pb.CountRequest{Counter: "unseeded"}); err == nil {
+		t.Errorf("Count was not seeded from the native counter but succeeded")
+	}
+}
+
+func TestCountRejectsBadRequests(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+
+	if _, err := s.Count(context.Background(), &pb.CountRequest{}); err == nil {
+		t.Errorf("Count without a name should fail")
+	}
+	if _, err := s.Count(context.Background(), &pb.CountRequest{Counter: "neg", Delta: -1}); err == nil {
+		t.Errorf("Negative deltas should fail")
+	}
+}
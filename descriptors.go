@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Registered file descriptors are stored under this prefix, one key per proto file
+const descriptorPrefix = reservedPrefix + "descriptors/"
+
+var (
+	descriptorFiles = flag.String("descriptors", "", "Comma separated FileDescriptorSet files to register on startup")
+)
+
+// descriptorRegistry knows the schemas of stored messages, falling back to
+// the types compiled into the binary
+type descriptorRegistry struct {
+	lock  sync.RWMutex
+	raw   map[string]*descriptorpb.FileDescriptorProto
+	files *protoregistry.Files
+
+	// Held from building a new registry until it is swapped in, so registrations don't race
+	update sync.Mutex
+}
+
+func newDescriptorRegistry() *descriptorRegistry {
+	return &descriptorRegistry{
+		raw:   make(map[string]*descriptorpb.FileDescriptorProto),
+		files: &protoregistry.Files{},
+	}
+}
+
+// fallbackResolver looks in the given files and then the global registry
+type fallbackResolver struct {
+	files *protoregistry.Files
+}
+
+func (f *fallbackResolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if fd, err := f.files.FindFileByPath(path); err == nil {
+		return fd, nil
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+func (f *fallbackResolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if d, err := f.files.FindDescriptorByName(name); err == nil {
+		return d, nil
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
+
+// add registers every file in the set, a file that is already registered is replaced.
+// Nothing changes if any file fails to build.
+func (r *descriptorRegistry) add(set *descriptorpb.FileDescriptorSet) ([]*descriptorpb.FileDescriptorProto, error) {
+	r.update.Lock()
+	defer r.update.Unlock()
+	raw, files, added, err := r.build(set)
+	if err != nil {
+		return nil, err
+	}
+	r.swap(raw, files)
+	return added, nil
+}
+
+// build links the registry as it would be with set added, without changing it. Must hold the update lock.
+func (r *descriptorRegistry) build(set *descriptorpb.FileDescriptorSet) (map[string]*descriptorpb.FileDescriptorProto, *protoregistry.Files, []*descriptorpb.FileDescriptorProto, error) {
+	r.lock.RLock()
+	raw := make(map[string]*descriptorpb.FileDescriptorProto)
+	for name, fdp := range r.raw {
+		raw[name] = fdp
+	}
+	r.lock.RUnlock()
+
+	var added []*descriptorpb.FileDescriptorProto
+	for _, fdp := range set.GetFile() {
+		if fdp.GetName() == "" {
+			return nil, nil, nil, fmt.Errorf("file descriptor has no name")
+		}
+		// Well known types are compiled in already
+		if _, err := protoregistry.GlobalFiles.FindFileByPath(fdp.GetName()); err == nil && strings.HasPrefix(fdp.GetName(), "google/protobuf/") {
+			continue
+		}
+		raw[fdp.GetName()] = fdp
+		added = append(added, fdp)
+	}
+
+	files, err := buildFiles(raw)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return raw, files, added, nil
+}
+
+// swap puts a registry made by build in place. Must hold the update lock.
+func (r *descriptorRegistry) swap(raw map[string]*descriptorpb.FileDescriptorProto, files *protoregistry.Files) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.raw = raw
+	r.files = files
+}
+
+// buildFiles links the raw descriptors in dependency order
+func buildFiles(raw map[string]*descriptorpb.FileDescriptorProto) (*protoregistry.Files, error) {
+	files := &protoregistry.Files{}
+	resolver := &fallbackResolver{files: files}
+
+	var build func(name string, seen map[string]bool) error
+	build = func(name string, seen map[string]bool) error {
+		if _, err := files.FindFileByPath(name); err == nil {
+			return nil
+		}
+		fdp, ok := raw[name]
+		if !ok {
+			if _, err := protoregistry.GlobalFiles.FindFileByPath(name); err == nil {
+				return nil
+			}
+			return fmt.Errorf("missing dependency %v", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("import cycle through %v", name)
+		}
+		seen[name] = true
+		for _, dep := range fdp.GetDependency() {
+			if err := build(dep, seen); err != nil {
+				return fmt.Errorf("%v: %w", name, err)
+			}
+		}
+
+		fd, err := protodesc.NewFile(fdp, resolver)
+		if err != nil {
+			return fmt.Errorf("unable to build %v: %w", name, err)
+		}
+		return files.RegisterFile(fd)
+	}
+
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := build(name, make(map[string]bool)); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// messages lists the full names of every registered message
+func (r *descriptorRegistry) messages() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var names []string
+	var walk func(msgs protoreflect.MessageDescriptors)
+	walk = func(msgs protoreflect.MessageDescriptors) {
+		for i := 0; i < msgs.Len(); i++ {
+			names = append(names, string(msgs.Get(i).FullName()))
+			walk(msgs.Get(i).Messages())
+		}
+	}
+	r.files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		walk(fd.Messages())
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+func (r *descriptorRegistry) FindMessageByName(name protoreflect.FullName) (protoreflect.MessageType, error) {
+	if r != nil {
+		r.lock.RLock()
+		d, err := r.files.FindDescriptorByName(name)
+		r.lock.RUnlock()
+		if err == nil {
+			if md, ok := d.(protoreflect.MessageDescriptor); ok {
+				return dynamicpb.NewMessageType(md), nil
+			}
+		}
+	}
+	return protoregistry.GlobalTypes.FindMessageByName(name)
+}
+
+func (r *descriptorRegistry) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	name := url
+	if i := strings.LastIndex(url, "/"); i >= 0 {
+		name = url[i+1:]
+	}
+	return r.FindMessageByName(protoreflect.FullName(name))
+}
+
+func (r *descriptorRegistry) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	return protoregistry.GlobalTypes.FindExtensionByName(field)
+}
+
+func (r *descriptorRegistry) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	return protoregistry.GlobalTypes.FindExtensionByNumber(message, field)
+}
+
+// unpack decodes an Any into a message, returning NotFound if the type is unknown
+func (r *descriptorRegistry) unpack(value *anypb.Any) (proto.Message, error) {
+	if value.GetTypeUrl() == "" {
+		return nil, status.Errorf(codes.NotFound, "value has no type")
+	}
+	mt, err := r.FindMessageByURL(value.GetTypeUrl())
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "unknown type %v", value.GetTypeUrl())
+	}
+	msg := mt.New().Interface()
+	if err := (proto.UnmarshalOptions{Resolver: r}).Unmarshal(value.GetValue(), msg); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "value is not a valid %v: %v", value.GetTypeUrl(), err)
+	}
+	return msg, nil
+}
+
+// renderJSON renders a value as JSON if its type is known
+func (r *descriptorRegistry) renderJSON(value *anypb.Any) (string, error) {
+	msg, err := r.unpack(value)
+	if err != nil {
+		return "", err
+	}
+	data, err := protojson.MarshalOptions{Resolver: r}.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// validate rejects values which claim a known type but do not parse as it
+func (r *descriptorRegistry) validate(value *anypb.Any) error {
+	if _, err := r.unpack(value); err != nil && status.Code(err) != codes.NotFound {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) RegisterDescriptors(ctx context.Context, req *pb.RegisterDescriptorsRequest) (*pb.RegisterDescriptorsResponse, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(req.GetDescriptorSet(), set); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "bad descriptor set: %v", err)
+	}
+	for _, fdp := range set.GetFile() {
+		if err := s.authorize(ctx, rightWrite, descriptorPrefix+fdp.GetName()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.registerDescriptors(ctx, set); err != nil {
+		return nil, err
+	}
+
+	return &pb.RegisterDescriptorsResponse{Messages: s.registry.messages()}, nil
+}
+
+// registerDescriptors persists the new files before the registry starts using them, so
+// nothing is decoded or indexed with a type that would be gone after a restart
+func (s *Server) registerDescriptors(ctx context.Context, set *descriptorpb.FileDescriptorSet) error {
+	s.registry.update.Lock()
+	defer s.registry.update.Unlock()
+	raw, files, added, err := s.registry.build(set)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	for _, fdp := range added {
+		value, err := anypb.New(fdp)
+		if err != nil {
+			return err
+		}
+		key := descriptorPrefix + fdp.GetName()
+		rec := s.newAudit(ctx, "write", key, value.GetValue())
+		_, err = s.write(ctx, "", &pb.WriteRequest{Key: key, Value: value}, rec)
+		s.finishAudit(rec, err)
+		if err != nil {
+			return fmt.Errorf("unable to persist %v: %w", fdp.GetName(), err)
+		}
+	}
+
+	s.registry.swap(raw, files)
+	return nil
+}
+
+// loadDescriptors restores the registry from pstore and then adds the files named on the command line
+func (s *Server) loadDescriptors(ctx context.Context, paths string) error {
+	keys, err := s.getKeys(ctx, &pb.GetKeysRequest{Prefix: descriptorPrefix})
+	if err != nil {
+		return err
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, key := range keys.GetKeys() {
+		resp, err := s.read(ctx, &pb.ReadRequest{Key: key})
+		if err != nil {
+			return err
+		}
+		fdp := &descriptorpb.FileDescriptorProto{}
+		if err := resp.GetValue().UnmarshalTo(fdp); err != nil {
+			return fmt.Errorf("bad stored descriptor %v: %w", key, err)
+		}
+		set.File = append(set.File, fdp)
+	}
+	if _, err := s.registry.add(set); err != nil {
+		return err
+	}
+
+	for _, path := range strings.Split(paths, ",") {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fset := &descriptorpb.FileDescriptorSet{}
+		if err := proto.Unmarshal(data, fset); err != nil {
+			return fmt.Errorf("bad descriptor set %v: %w", path, err)
+		}
+		if err := s.registerDescriptors(ctx, fset); err != nil {
+			return fmt.Errorf("unable to register %v: %w", path, err)
+		}
+	}
+
+	log.Printf("Descriptor registry knows %v messages", len(s.registry.messages()))
+	return nil
+}
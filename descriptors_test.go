@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// testDescriptors describes test.Thing, a message this binary knows nothing about
+func testDescriptors() *descriptorpb.FileDescriptorSet {
+	return &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("test/thing.proto"),
+			Package: proto.String("test"),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("Thing"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("name"), JsonName: proto.String("name"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+					{Name: proto.String("size"), JsonName: proto.String("size"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+				},
+			}},
+		}},
+	}
+}
+
+// thing builds a test.Thing by hand: field 1 is the name, field 2 the size
+func thing(name string, size int32) *anypb.Any {
+	data := append([]byte{0x0a, byte(len(name))}, name...)
+	data = append(data, 0x10, byte(size))
+	return &anypb.Any{TypeUrl: "type.googleapis.com/test.Thing", Value: data}
+}
+
+func TestRegisterDescriptors(t *testing.T) {
+	primary := getTestBackend("primary")
+	s := getTestServer(primary)
+
+	data, _ := proto.Marshal(testDescriptors())
+	resp, err := s.RegisterDescriptors(context.Background(), &pb.RegisterDescriptorsRequest{DescriptorSet: data})
+	if err != nil {
+		t.Fatalf("Bad register: %v", err)
+	}
+	if len(resp.GetMessages()) != 1 || resp.GetMessages()[0] != "test.Thing" {
+		t.Errorf("Bad messages: %v", resp)
+	}
+
+	if _, err := s.Write(context.Background(), &pb.WriteRequest{Key: "things/one", Value: thing("one", 5)}); err != nil {
+		t.Fatalf("Bad write: %v", err)
+	}
+	bad := &anypb.Any{TypeUrl: "type.googleapis.com/test.Thing", Value: []byte{0xff, 0xff}}
+	if _, err := s.Write(context.Background(), &pb.WriteRequest{Key: "things/bad", Value: bad}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Invalid value should be rejected: %v", err)
+	}
+
+	// A restarted server should pick the registry back up from storage
+	s = getTestServer(primary)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.loadDescriptors(ctx, ""); err != nil {
+		t.Fatalf("Bad load: %v", err)
+	}
+
+	read, err := s.Read(ctx, &pb.ReadRequest{Key: "things/one", RenderJson: true})
+	if err != nil {
+		t.Fatalf("Bad read: %v", err)
+	}
+	if !strings.Contains(read.GetJson(), `"name":"one"`) || !strings.Contains(read.GetJson(), `"size":5`) {
+		t.Errorf("Bad JSON: %v", read.GetJson())
+	}
+}
+
+func TestRegisterDescriptorsMissingDependency(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+
+	set := testDescriptors()
+	set.File[0].Dependency = []string{"missing.proto"}
+	if err := s.registerDescriptors(context.Background(), set); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Missing dependency should be rejected: %v", err)
+	}
+	if len(s.registry.messages()) != 0 {
+		t.Errorf("Failed registration changed the registry: %v", s.registry.messages())
+	}
+}
+
+func TestRegisterDescriptorsPersistsFirst(t *testing.T) {
+	primary := getTestBackend("primary")
+	s := getTestServer(primary)
+
+	primary.down = true
+	if err := s.registerDescriptors(context.Background(), testDescriptors()); err == nil {
+		t.Fatalf("Registration should fail when it cannot be persisted")
+	}
+	if len(s.registry.messages()) != 0 {
+		t.Errorf("Unpersisted registration changed the registry: %v", s.registry.messages())
+	}
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+var (
+	keyringFile       = flag.String("keyring", "", "Path to the JSON keyring, setting this enables encryption of values at rest")
+	reencryptInterval = flag.Duration("reencrypt_interval", time.Hour*24, "How often to rewrap values sealed with a key that is no longer current")
+)
+
+var (
+	encryptCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_encrypt_count",
+	}, []string{"key_id", "op"})
+	reencryptCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_reencrypt_count",
+	}, []string{"code"})
+)
+
+// Encrypted values start with this header followed by a serialized EncryptedValue
+var encryptionHeader = []byte{0x00, 'P', 'E'}
+
+// keyringFormat is the on disk keyring, keys are base64 encoded 16, 24 or 32 byte AES keys
+//
+//	{
+//	  "keys": {"2024": "...", "2025": "..."},
+//	  "default": "2025",
+//	  "prefixes": {"secrets/": "2025"}
+//	}
+type keyringFormat struct {
+	Keys     map[string]string `json:"keys"`
+	Default  string            `json:"default"`
+	Prefixes map[string]string `json:"prefixes"`
+}
+
+type keyring struct {
+	keys     map[string]cipher.AEAD
+	def      string
+	prefixes map[string]string
+}
+
+func loadKeyring(path string) (*keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseKeyring(data)
+}
+
+func parseKeyring(data []byte) (*keyring, error) {
+	format := &keyringFormat{}
+	if err := json.Unmarshal(data, format); err != nil {
+		return nil, fmt.Errorf("bad keyring: %w", err)
+	}
+
+	k := &keyring{keys: make(map[string]cipher.AEAD), def: format.Default, prefixes: format.Prefixes}
+	for id, encoded := range format.Keys {
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("bad key %v: %w", id, err)
+		}
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return nil, fmt.Errorf("bad key %v: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[id] = aead
+	}
+
+	if _, ok := k.keys[k.def]; k.def != "" && !ok {
+		return nil, fmt.Errorf("default key %v is not in the keyring", k.def)
+	}
+	for prefix, id := range k.prefixes {
+		if _, ok := k.keys[id]; id != "" && !ok {
+			return nil, fmt.Errorf("key %v for prefix %v is not in the keyring", id, prefix)
+		}
+	}
+
+	return k, nil
+}
+
+// keyFor returns the ID of the key new values under key should be sealed with, the
+// longest matching prefix wins and an empty ID means the value is stored in the clear.
+// pstore's own keys are left in the clear, but namespaced keys hold caller data.
+func (k *keyring) keyFor(key string) string {
+	if strings.HasPrefix(key, reservedPrefix) && !strings.HasPrefix(key, namespacePrefix) {
+		return ""
+	}
+
+	best := -1
+	id := k.def
+	for prefix, pid := range k.prefixes {
+		if strings.HasPrefix(key, prefix) && len(prefix) > best {
+			best = len(prefix)
+			id = pid
+		}
+	}
+	return id
+}
+
+func seal(aead cipher.AEAD, plaintext, data []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, data), nil
+}
+
+func unseal(aead cipher.AEAD, sealed, data []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("sealed value is too short")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], data)
+}
+
+// encrypt seals value with a fresh data key, the storage key is bound in as
+// additional data so a value cannot be moved to a different key
+func (k *keyring) encrypt(key string, value []byte) ([]byte, error) {
+	if k == nil {
+		return value, nil
+	}
+	id := k.keyFor(key)
+	if id == "" {
+		return value, nil
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := seal(aead, value, []byte(key))
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := seal(k.keys[id], dataKey, []byte(id))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := proto.Marshal(&pb.EncryptedValue{KeyId: id, WrappedKey: wrapped, Ciphertext: ciphertext})
+	if err != nil {
+		return nil, err
+	}
+	encryptCount.With(prometheus.Labels{"key_id": id, "op": "encrypt"}).Inc()
+	return append(append([]byte{}, encryptionHeader...), data...), nil
+}
+
+func parseEncrypted(value []byte) (*pb.EncryptedValue, bool, error) {
+	if len(value) <= len(encryptionHeader) || !bytes.HasPrefix(value, encryptionHeader) {
+		return nil, false, nil
+	}
+	ev := &pb.EncryptedValue{}
+	if err := proto.Unmarshal(value[len(encryptionHeader):], ev); err != nil {
+		return nil, true, fmt.Errorf("bad encrypted value: %w", err)
+	}
+	return ev, true, nil
+}
+
+// unwrap opens the data key of an encrypted value
+func (k *keyring) unwrap(ev *pb.EncryptedValue) ([]byte, error) {
+	if k == nil {
+		return nil, fmt.Errorf("value is encrypted with %v but no keyring is loaded", ev.GetKeyId())
+	}
+	kek, ok := k.keys[ev.GetKeyId()]
+	if !ok {
+		return nil, fmt.Errorf("key %v is not in the keyring", ev.GetKeyId())
+	}
+	return unseal(kek, ev.GetWrappedKey(), []byte(ev.GetKeyId()))
+}
+
+// decrypt undoes encrypt, values stored without the header are returned unchanged
+func (k *keyring) decrypt(key string, value []byte) ([]byte, error) {
+	ev, encrypted, err := parseEncrypted(value)
+	if !encrypted || err != nil {
+		return value, err
+	}
+
+	dataKey, err := k.unwrap(ev)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptCount.With(prometheus.Labels{"key_id": ev.GetKeyId(), "op": "decrypt"}).Inc()
+	return unseal(aead, ev.GetCiphertext(), []byte(key))
+}
+
+// rotate returns the stored value for key resealed under its current key. Values sealed with
+// an old key only have their data key rewrapped, values stored in the clear are encrypted.
+// The second return is false when the value is already up to date.
+func (k *keyring) rotate(key string, value []byte) ([]byte, bool, error) {
+	id := k.keyFor(key)
+	ev, encrypted, err := parseEncrypted(value)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !encrypted {
+		if id == "" {
+			return value, false, nil
+		}
+		nvalue, err := k.encrypt(key, value)
+		return nvalue, true, err
+	}
+
+	if ev.GetKeyId() == id {
+		return value, false, nil
+	}
+
+	if id == "" {
+		plain, err := k.decrypt(key, value)
+		return plain, true, err
+	}
+
+	dataKey, err := k.unwrap(ev)
+	if err != nil {
+		return nil, false, err
+	}
+
+	wrapped, err := seal(k.keys[id], dataKey, []byte(id))
+	if err != nil {
+		return nil, false, err
+	}
+	data, err := proto.Marshal(&pb.EncryptedValue{KeyId: id, WrappedKey: wrapped, Ciphertext: ev.GetCiphertext()})
+	if err != nil {
+		return nil, false, err
+	}
+	return append(append([]byte{}, encryptionHeader...), data...), true, nil
+}
+
+func (s *Server) runReencryption() {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+		count, err := s.reencrypt(ctx)
+		cancel()
+		log.Printf("Re-encrypted %v values: %v", count, err)
+
+		time.Sleep(*reencryptInterval)
+	}
+}
+
+// reencrypt walks every key in the primary and reseals anything not under its current key
+func (s *Server) reencrypt(ctx context.Context) (int, error) {
+	keys, err := s.runGetKeys(ctx, s.clients[0], &pb.GetKeysRequest{AllKeys: true})
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, key := range keys.GetKeys() {
+		if s.reencryptKey(ctx, key) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// reencryptKey reseals a single key, holding the key lock so a concurrent write is not
+// overwritten with the value read here. Reports whether the key was rewritten.
+func (s *Server) reencryptKey(ctx context.Context, key string) bool {
+	if strings.HasPrefix(key, trashPrefix) || strings.HasPrefix(key, changelogPrefix) {
+		return s.reencryptEntry(ctx, key)
+	}
+
+	defer s.lockKey(key)()
+	resp, err := s.runRead(ctx, s.clients[0], &pb.ReadRequest{Key: key})
+	if err != nil {
+		reencryptCount.With(prometheus.Labels{"code": "read_fail"}).Inc()
+		return false
+	}
+
+	body, err := verifyChecksum(key, resp.GetValue().GetValue())
+	if err != nil {
+		log.Printf("Unable to re-encrypt %v: %v", key, err)
+		reencryptCount.With(prometheus.Labels{"code": "checksum_fail"}).Inc()
+		return false
+	}
+
+	typeUrl, body, err := unwrapType(body)
+	if err != nil {
+		log.Printf("Unable to re-encrypt %v: %v", key, err)
+		reencryptCount.With(prometheus.Labels{"code": "type_fail"}).Inc()
+		return false
+	}
+	if typeUrl == "" {
+		typeUrl = resp.GetValue().GetTypeUrl()
+	}
+
+	value, changed, err := s.keyring.rotate(key, body)
+	if err != nil {
+		log.Printf("Unable to re-encrypt %v: %v", key, err)
+		reencryptCount.With(prometheus.Labels{"code": "rotate_fail"}).Inc()
+		return false
+	}
+	if !changed {
+		return false
+	}
+
+	value, err = wrapType(typeUrl, value)
+	if err != nil {
+		reencryptCount.With(prometheus.Labels{"code": "type_fail"}).Inc()
+		return false
+	}
+	_, err = s.writeAll(ctx, &pb.WriteRequest{
+		Key:   key,
+		Value: &anypb.Any{TypeUrl: typeUrl, Value: addChecksum(key, value)},
+	}, nil)
+	if err != nil {
+		reencryptCount.With(prometheus.Labels{"code": "write_fail"}).Inc()
+		return false
+	}
+	reencryptCount.With(prometheus.Labels{"code": "OK"}).Inc()
+	return true
+}
+
+// sealedEntry is a trash or changelog entry, which holds a value sealed as it would be under Key
+type sealedEntry interface {
+	proto.Message
+	GetKey() string
+	GetValue() *anypb.Any
+}
+
+// reencryptEntry reseals the value held inside a trash or changelog entry. Reports
+// whether the entry was rewritten.
+func (s *Server) reencryptEntry(ctx context.Context, key string) bool {
+	defer s.lockKey(key)()
+	resp, err := s.runRead(ctx, s.clients[0], &pb.ReadRequest{Key: key})
+	if err != nil {
+		reencryptCount.With(prometheus.Labels{"code": "read_fail"}).Inc()
+		return false
+	}
+	data, err := s.decodeValue(key, resp.GetValue())
+	if err != nil {
+		log.Printf("Unable to re-encrypt %v: %v", key, err)
+		reencryptCount.With(prometheus.Labels{"code": "decode_fail"}).Inc()
+		return false
+	}
+
+	var entry sealedEntry = &pb.ChangeEvent{}
+	if strings.HasPrefix(key, trashPrefix) {
+		entry = &pb.TrashEntry{}
+	}
+	if err := proto.Unmarshal(data.GetValue(), entry); err != nil {
+		log.Printf("Unable to re-encrypt %v: %v", key, err)
+		reencryptCount.With(prometheus.Labels{"code": "decode_fail"}).Inc()
+		return false
+	}
+	if entry.GetValue() == nil {
+		return false
+	}
+
+	value, changed, err := s.keyring.rotate(entry.GetKey(), entry.GetValue().GetValue())
+	if err != nil {
+		log.Printf("Unable to re-encrypt %v: %v", key, err)
+		reencryptCount.With(prometheus.Labels{"code": "rotate_fail"}).Inc()
+		return false
+	}
+	if !changed {
+		return false
+	}
+	entry.GetValue().Value = value
+
+	body, err := proto.Marshal(entry)
+	if err != nil {
+		reencryptCount.With(prometheus.Labels{"code": "encode_fail"}).Inc()
+		return false
+	}
+	encoded, err := s.encodeValue(key, &anypb.Any{TypeUrl: data.GetTypeUrl(), Value: body})
+	if err != nil {
+		reencryptCount.With(prometheus.Labels{"code": "encode_fail"}).Inc()
+		return false
+	}
+	_, err = s.writeAll(ctx, &pb.WriteRequest{Key: key, Value: &anypb.Any{TypeUrl: data.GetTypeUrl(), Value: encoded}}, nil)
+	if err != nil {
+		reencryptCount.With(prometheus.Labels{"code": "write_fail"}).Inc()
+		return false
+	}
+	reencryptCount.With(prometheus.Labels{"code": "OK"}).Inc()
+	return true
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func testKeyring(t *testing.T, def string) *keyring {
+	k, err := parseKeyring([]byte(fmt.Sprintf(`{
+		"keys": {"old": "%v", "new": "%v"},
+		"default": "%v",
+		"prefixes": {"public/": ""}
+	}`, base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)),
+		base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32)), def)))
+	if err != nil {
+		t.Fatalf("Bad keyring: %v", err)
+	}
+	return k
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	k := testKeyring(t, "old")
+
+	value := []byte("the secret value")
+	sealed, err := k.encrypt("secret", value)
+	if err != nil {
+		t.Fatalf("Bad encrypt: %v", err)
+	}
+	if bytes.Contains(sealed, value) {
+		t.Errorf("Value was stored in the clear")
+	}
+
+	plain, err := k.decrypt("secret", sealed)
+	if err != nil || !bytes.Equal(plain, value) {
+		t.Errorf("Bad decrypt: %v, %v", plain, err)
+	}
+
+	if _, err := k.decrypt("other", sealed); err == nil {
+		t.Errorf("Value should not decrypt under a different key")
+	}
+
+	clear, _ := k.encrypt("public/thing", value)
+	if !bytes.Equal(clear, value) {
+		t.Errorf("Public prefix should not be encrypted")
+	}
+	clear, _ = k.encrypt(counterKey("hits"), value)
+	if !bytes.Equal(clear, value) {
+		t.Errorf("Internal keys should not be encrypted")
+	}
+	sealed, _ = k.encrypt(namespacedKey("team", "secret"), value)
+	if bytes.Contains(sealed, value) {
+		t.Errorf("Namespaced value was stored in the clear")
+	}
+}
+
+func TestReencryption(t *testing.T) {
+	primary := getTestBackend("primary")
+	secondary := getTestBackend("secondary")
+	s := getTestServer(primary, secondary)
+
+	// Written before encryption was turned on
+	s.Write(context.Background(), &pb.WriteRequest{Key: "plain", Value: &anypb.Any{Value: []byte("one")}})
+
+	s.keyring = testKeyring(t, "old")
+	s.Write(context.Background(), &pb.WriteRequest{Key: "sealed", Value: &anypb.Any{Value: []byte("two")}})
+
+	s.keyring = testKeyring(t, "new")
+	count, err := s.reencrypt(context.Background())
+	if err != nil || count != 2 {
+		t.Fatalf("Bad re-encryption: %v, %v", count, err)
+	}
+
+	for key, value := range map[string]string{"plain": "one", "sealed": "two"} {
+		for _, b := range []*testBackend{primary, secondary} {
+			raw, _ := b.Read(context.Background(), &pb.ReadRequest{Key: key})
+			body, _ := verifyChecksum(key, raw.GetValue().GetValue())
+			_, body, _ = unwrapType(body)
+			ev, encrypted, err := parseEncrypted(body)
+			if !encrypted || err != nil || ev.GetKeyId() != "new" {
+				t.Errorf("%v on %v was not rotated: %v, %v", key, b.Name(), ev, err)
+			}
+		}
+
+		resp, err := s.Read(context.Background(), &pb.ReadRequest{Key: key})
+		if err != nil || string(resp.GetValue().GetValue()) != value {
+			t.Errorf("Bad read of %v after rotation: %v, %v", key, resp, err)
+		}
+	}
+
+	count, err = s.reencrypt(context.Background())
+	if err != nil || count != 0 {
+		t.Errorf("Second pass should have nothing to do: %v, %v", count, err)
+	}
+}
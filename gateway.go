@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+var (
+	httpMaxBody = flag.Int64("http_max_body", 64<<20, "Largest request body the HTTP gateway reads, in bytes")
+)
+
+var (
+	httpCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_http_count",
+	}, []string{"method", "code"})
+)
+
+// keyJSON is how a value is rendered over HTTP, value holds the message as JSON
+// when the type is registered and base64 holds the raw bytes otherwise
+type keyJSON struct {
+	Key       string          `json:"key,omitempty"`
+	TypeUrl   string          `json:"type_url,omitempty"`
+	Timestamp int64           `json:"timestamp,omitempty"`
+	Value     json.RawMessage `json:"value,omitempty"`
+	Base64    string          `json:"base64,omitempty"`
+}
+
+func etag(value *anypb.Any) string {
+	sum := sha256.New()
+	sum.Write([]byte(value.GetTypeUrl()))
+	sum.Write(value.GetValue())
+	return `"` + hex.EncodeToString(sum.Sum(nil)[:16]) + `"`
+}
+
+var httpCodes = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.NotFound:           http.StatusNotFound,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.FailedPrecondition: http.StatusPreconditionFailed,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+}
+
+func writeHTTPError(w http.ResponseWriter, r *http.Request, err error) {
+	code, ok := httpCodes[status.Code(err)]
+	if !ok {
+		code = http.StatusInternalServerError
+	}
+	httpCount.With(prometheus.Labels{"method": r.Method, "code": fmt.Sprintf("%v", status.Code(err))}).Inc()
+	http.Error(w, status.Convert(err).Message(), code)
+}
+
+func writeHTTPJSON(w http.ResponseWriter, r *http.Request, resp interface{}) {
+	httpCount.With(prometheus.Labels{"method": r.Method, "code": "OK"}).Inc()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// httpContext carries the request namespace, if any, through to the gRPC handlers
+func httpContext(r *http.Request) (context.Context, context.CancelFunc) {
+	ctx := r.Context()
+	if ns := r.Header.Get("X-Pstore-Namespace"); ns != "" {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(namespaceMetadata, ns))
+	}
+	return context.WithTimeout(ctx, time.Minute)
+}
+
+// registerGateway adds the HTTP/JSON view of PStoreService to mux
+func (s *Server) registerGateway(mux *http.ServeMux) {
+	mux.HandleFunc("GET /v1/keys/{key...}", s.httpAuth("Read", s.httpRead))
+	mux.HandleFunc("PUT /v1/keys/{key...}", s.httpAuth("Write", s.httpWrite))
+	mux.HandleFunc("DELETE /v1/keys/{key...}", s.httpAuth("Delete", s.httpDelete))
+	mux.HandleFunc("GET /v1/keys", s.httpAuth("GetKeys", s.httpGetKeys))
+	mux.HandleFunc("POST /v1/counters/{name...}", s.httpAuth("Count", s.httpCount))
+}
+
+func (s *Server) renderKey(key string, resp *pb.ReadResponse) keyJSON {
+	rendered := keyJSON{Key: key, TypeUrl: resp.GetValue().GetTypeUrl(), Timestamp: resp.GetTimestamp()}
+	if msg, err := s.registry.unpack(resp.GetValue()); err == nil {
+		if data, err := (protojson.MarshalOptions{Resolver: s.registry}).Marshal(msg); err == nil {
+			rendered.Value = data
+			return rendered
+		}
+	}
+	rendered.Base64 = base64.StdEncoding.EncodeToString(resp.GetValue().GetValue())
+	return rendered
+}
+
+func (s *Server) httpRead(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := httpContext(r)
+	defer cancel()
+
+	key := r.PathValue("key")
+	resp, err := s.Read(ctx, &pb.ReadRequest{Key: key})
+	if err != nil {
+		writeHTTPError(w, r, err)
+		return
+	}
+
+	tag := etag(resp.GetValue())
+	w.Header().Set("ETag", tag)
+	if r.Header.Get("If-None-Match") == tag {
+		httpCount.With(prometheus.Labels{"method": r.Method, "code": "OK"}).Inc()
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeHTTPJSON(w, r, s.renderKey(key, resp))
+}
+
+type preconditionsKey struct{}
+
+type preconditions struct {
+	match     string
+	noneMatch string
+}
+
+// withPreconditions carries If-Match and If-None-Match through to the write path,
+// where they are checked under the key lock
+func withPreconditions(ctx context.Context, r *http.Request) context.Context {
+	p := preconditions{match: r.Header.Get("If-Match"), noneMatch: r.Header.Get("If-None-Match")}
+	if p.match == "" && p.noneMatch == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, preconditionsKey{}, p)
+}
+
+// checkPreconditions applies any preconditions carried by ctx against the stored value
+// of key. Must hold the key lock.
+func (s *Server) checkPreconditions(ctx context.Context, key string) error {
+	p, ok := ctx.Value(preconditionsKey{}).(preconditions)
+	if !ok {
+		return nil
+	}
+
+	current := ""
+	resp, err := s.read(ctx, &pb.ReadRequest{Key: key})
+	if err == nil {
+		current = etag(resp.GetValue())
+	} else if status.Code(err) != codes.NotFound {
+		return err
+	}
+
+	if p.match != "" && (current == "" || (p.match != "*" && p.match != current)) {
+		return status.Errorf(codes.FailedPrecondition, "value does not match %v", p.match)
+	}
+	if p.noneMatch != "" && current != "" && (p.noneMatch == "*" || p.noneMatch == current) {
+		return status.Errorf(codes.FailedPrecondition, "value matches %v", p.noneMatch)
+	}
+	return nil
+}
+
+// parseValue turns a request body into an Any, using the registry to encode JSON values
+func (s *Server) parseValue(body keyJSON) (*anypb.Any, error) {
+	if body.Base64 != "" {
+		data, err := base64.StdEncoding.DecodeString(body.Base64)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "bad base64: %v", err)
+		}
+		return &anypb.Any{TypeUrl: body.TypeUrl, Value: data}, nil
+	}
+
+	mt, err := s.registry.FindMessageByURL(body.TypeUrl)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "type %q is not registered, send the value as base64", body.TypeUrl)
+	}
+	msg := mt.New().Interface()
+	if err := (protojson.UnmarshalOptions{Resolver: s.registry}).Unmarshal(body.Value, msg); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "value is not a valid %v: %v", body.TypeUrl, err)
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return &anypb.Any{TypeUrl: body.TypeUrl, Value: data}, nil
+}
+
+func (s *Server) httpWrite(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := httpContext(r)
+	defer cancel()
+
+	key := r.PathValue("key")
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, *httpMaxBody))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		httpCount.With(prometheus.Labels{"method": r.Method, "code": "TooLarge"}).Inc()
+		http.Error(w, fmt.Sprintf("body is larger than %v bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		writeHTTPError(w, r, status.Errorf(codes.InvalidArgument, "bad body: %v", err))
+		return
+	}
+	body := keyJSON{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		writeHTTPError(w, r, status.Errorf(codes.InvalidArgument, "bad body: %v", err))
+		return
+	}
+	value, err := s.parseValue(body)
+	if err != nil {
+		writeHTTPError(w, r, err)
+		return
+	}
+
+	resp, err := s.Write(withPreconditions(ctx, r), &pb.WriteRequest{Key: key, Value: value})
+	if err != nil {
+		writeHTTPError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(value))
+	writeHTTPJSON(w, r, keyJSON{Key: key, TypeUrl: value.GetTypeUrl(), Timestamp: resp.GetTimestamp()})
+}
+
+func (s *Server) httpDelete(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := httpContext(r)
+	defer cancel()
+
+	key := r.PathValue("key")
+	if _, err := s.Delete(withPreconditions(ctx, r), &pb.DeleteRequest{Key: key, Hard: r.URL.Query().Get("hard") == "true"}); err != nil {
+		writeHTTPError(w, r, err)
+		return
+	}
+	writeHTTPJSON(w, r, keyJSON{Key: key})
+}
+
+func (s *Server) httpGetKeys(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := httpContext(r)
+	defer cancel()
+
+	prefix := r.URL.Query().Get("prefix")
+	resp, err := s.GetKeys(ctx, &pb.GetKeysRequest{Prefix: prefix, AllKeys: prefix == ""})
+	if err != nil {
+		writeHTTPError(w, r, err)
+		return
+	}
+	keys := resp.GetKeys()
+	if keys == nil {
+		keys = []string{}
+	}
+	writeHTTPJSON(w, r, map[string][]string{"keys": keys})
+}
+
+func (s *Server) httpCount(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := httpContext(r)
+	defer cancel()
+
+	delta := int64(0)
+	if d := r.URL.Query().Get("delta"); d != "" {
+		var err error
+		delta, err = strconv.ParseInt(d, 10, 64)
+		if err != nil {
+			writeHTTPError(w, r, status.Errorf(codes.InvalidArgument, "bad delta %q", d))
+			return
+		}
+	}
+
+	resp, err := s.Count(ctx, &pb.CountRequest{Counter: r.PathValue("name"), Delta: delta})
+	if err != nil {
+		writeHTTPError(w, r, err)
+		return
+	}
+	writeHTTPJSON(w, r, map[string]int64{"count": resp.GetCount()})
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func runHTTP(t *testing.T, mux *http.ServeMux, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGateway(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+	s.registerDescriptors(context.Background(), testDescriptors())
+	mux := http.NewServeMux()
+	s.registerGateway(mux)
+
+	rec := runHTTP(t, mux, "PUT", "/v1/keys/things/one", `{"type_url": "type.googleapis.com/test.Thing", "value": {"name": "one", "size": 3}}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Bad put: %v %v", rec.Code, rec.Body)
+	}
+	tag := rec.Header().Get("ETag")
+
+	rec = runHTTP(t, mux, "PUT", "/v1/keys/raw", `{"base64": "AQID"}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Bad raw put: %v %v", rec.Code, rec.Body)
+	}
+
+	rec = runHTTP(t, mux, "GET", "/v1/keys/things/one", "", nil)
+	body := keyJSON{}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusOK || !strings.Contains(string(body.Value), `"size":3`) || rec.Header().Get("ETag") != tag {
+		t.Errorf("Bad get: %v %v", rec.Code, rec.Body)
+	}
+
+	rec = runHTTP(t, mux, "GET", "/v1/keys/raw", "", nil)
+	body = keyJSON{}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if body.Base64 != "AQID" {
+		t.Errorf("Unknown types should come back as base64: %v", rec.Body)
+	}
+
+	rec = runHTTP(t, mux, "GET", "/v1/keys?prefix=things/", "", nil)
+	if !strings.Contains(rec.Body.String(), "things/one") || strings.Contains(rec.Body.String(), "raw") {
+		t.Errorf("Bad listing: %v", rec.Body)
+	}
+
+	// Conditional write with a stale tag
+	rec = runHTTP(t, mux, "PUT", "/v1/keys/things/one", `{"base64": "AQID", "type_url": "type.googleapis.com/other"}`, map[string]string{"If-Match": `"stale"`})
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Stale write should fail: %v %v", rec.Code, rec.Body)
+	}
+	rec = runHTTP(t, mux, "PUT", "/v1/keys/things/one", `{"type_url": "type.googleapis.com/test.Thing", "value": {"name": "two"}}`, map[string]string{"If-Match": tag})
+	if rec.Code != http.StatusOK {
+		t.Errorf("Matching write should pass: %v %v", rec.Code, rec.Body)
+	}
+	rec = runHTTP(t, mux, "PUT", "/v1/keys/raw", `{"base64": "AQID"}`, map[string]string{"If-None-Match": "*"})
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Create only write over an existing key should fail: %v %v", rec.Code, rec.Body)
+	}
+
+	rec = runHTTP(t, mux, "DELETE", "/v1/keys/raw", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Bad delete: %v %v", rec.Code, rec.Body)
+	}
+	rec = runHTTP(t, mux, "GET", "/v1/keys/raw", "", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Key should be gone: %v %v", rec.Code, rec.Body)
+	}
+
+	runHTTP(t, mux, "POST", "/v1/counters/hits?delta=4", "", nil)
+	rec = runHTTP(t, mux, "POST", "/v1/counters/hits", "", nil)
+	if !strings.Contains(rec.Body.String(), `"count":5`) {
+		t.Errorf("Bad count: %v", rec.Body)
+	}
+}
+
+func TestGatewayConditionalRace(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"), getTestBackend("secondary"))
+	mux := http.NewServeMux()
+	s.registerGateway(mux)
+
+	// Only one of many racing creates may win
+	codes := make(chan int, 10)
+	waitgroup := &sync.WaitGroup{}
+	for i := 0; i < cap(codes); i++ {
+		waitgroup.Add(1)
+		go func() {
+			defer waitgroup.Done()
+			codes <- runHTTP(t, mux, "PUT", "/v1/keys/things/one", `{"base64": "AQID"}`, map[string]string{"If-None-Match": "*"}).Code
+		}()
+	}
+	waitgroup.Wait()
+	close(codes)
+	created := 0
+	for code := range codes {
+		if code == http.StatusOK {
+			created++
+		}
+	}
+	if created != 1 {
+		t.Errorf("Conditional create succeeded %v times", created)
+	}
+
+	if len(s.keyLocks) != 0 {
+		t.Errorf("Key locks were kept after use: %v", len(s.keyLocks))
+	}
+}
+
+func TestGatewayBodyLimit(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+	mux := http.NewServeMux()
+	s.registerGateway(mux)
+	old := *httpMaxBody
+	*httpMaxBody = 32
+	defer func() { *httpMaxBody = old }()
+
+	if rec := runHTTP(t, mux, "PUT", "/v1/keys/raw", `{"base64": "AQIDBAUGBwgJCgsMDQ4PEBESExQV"}`, nil); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Oversized body should be refused: %v", rec.Code)
+	}
+	if valueOf(s, "raw") != "NotFound" {
+		t.Errorf("Oversized body was written")
+	}
+	if rec := runHTTP(t, mux, "PUT", "/v1/keys/raw", `{"base64": "AQ=="}`, nil); rec.Code != http.StatusOK {
+		t.Errorf("Small body should be accepted: %v %v", rec.Code, rec.Body)
+	}
+}
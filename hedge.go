@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	hedgePercentile = flag.Float64("hedge_percentile", 0, "Repeat a read on a secondary once the primary is slower than this percentile of its recent reads, zero disables hedging")
+	hedgeMinDelay   = flag.Duration("hedge_min_delay", time.Millisecond*10, "Never hedge a read sooner than this")
+)
+
+// Primary read latencies kept for working out the hedge delay, which is
+// recomputed every hedgeRefresh reads
+const (
+	hedgeSamples = 1000
+	hedgeRefresh = 100
+
+	// How long a primary read is given once nobody is waiting on it
+	hedgePrimaryTimeout = time.Minute
+)
+
+var (
+	hedgeCount = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pstore_hedged_reads",
+	})
+	hedgeWins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_hedge_wins",
+	}, []string{"client"})
+	hedgeDelay = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pstore_hedge_delay_seconds",
+	})
+)
+
+type hedger struct {
+	lock       sync.Mutex
+	percentile float64
+	minDelay   time.Duration
+	samples    []time.Duration
+	next       int
+	delay      time.Duration
+}
+
+func newHedger(percentile float64, minDelay time.Duration) (*hedger, error) {
+	if percentile == 0 {
+		return nil, nil
+	}
+	if percentile < 0 || percentile > 100 {
+		return nil, fmt.Errorf("hedge percentile must be between 0 and 100, not %v", percentile)
+	}
+	return &hedger{percentile: percentile, minDelay: minDelay, delay: minDelay}, nil
+}
+
+func (h *hedger) observe(d time.Duration) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if len(h.samples) < hedgeSamples {
+		h.samples = append(h.samples, d)
+	} else {
+		h.samples[h.next%hedgeSamples] = d
+	}
+	h.next++
+
+	if h.next%hedgeRefresh == 0 {
+		sorted := slices.Clone(h.samples)
+		slices.Sort(sorted)
+		h.delay = max(h.minDelay, sorted[int(float64(len(sorted)-1)*h.percentile/100)])
+		hedgeDelay.Set(h.delay.Seconds())
+	}
+}
+
+func (h *hedger) wait() time.Duration {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.delay
+}
+
+type readResult struct {
+	client pstore
+	resp   *pb.ReadResponse
+	err    error
+}
+
+// readPrimary reads from the primary, repeating the read on the first secondary if the
+// primary is slow. The primary's answer is taken unless it failed, the secondary's only
+// if it is a good value; hedged reports that the secondary's answer was used.
+func (s *Server) readPrimary(ctx context.Context, req *pb.ReadRequest) (resp *pb.ReadResponse, hedged bool, err error) {
+	if s.hedger == nil || len(s.clients) < 2 {
+		resp, err := s.runRead(ctx, s.clients[0], req)
+		return resp, false, err
+	}
+
+	// A secondary read that loses is abandoned rather than counted against the secondary
+	hctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(errAbandoned)
+	results := make(chan readResult, 2)
+
+	// The primary is left to finish, even once the caller has gone, so slow reads
+	// still count towards the delay. One that times out counts as the timeout.
+	pctx, pcancel := context.WithTimeout(context.WithoutCancel(ctx), hedgePrimaryTimeout)
+	go func() {
+		defer pcancel()
+		t := time.Now()
+		resp, err := s.runRead(pctx, s.clients[0], req)
+		if err == nil || status.Code(err) == codes.DeadlineExceeded {
+			s.hedger.observe(time.Since(t))
+		}
+		results <- readResult{client: s.clients[0], resp: resp, err: err}
+	}()
+
+	timer := time.NewTimer(s.hedger.wait())
+	defer timer.Stop()
+
+	var primary *readResult
+	pending, sent := 1, false
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if primary == nil && !sent {
+				sent = true
+				pending++
+				hedgeCount.Inc()
+				go func() {
+					resp, err := s.runRead(hctx, s.clients[1], req)
+					results <- readResult{client: s.clients[1], resp: resp, err: err}
+				}()
+			}
+		case r := <-results:
+			pending--
+			if r.client == s.clients[0] {
+				if !sent || r.err == nil || status.Code(r.err) == codes.NotFound {
+					if sent {
+						hedgeWins.With(prometheus.Labels{"client": r.client.Name()}).Inc()
+					}
+					return r.resp, false, r.err
+				}
+				primary = &r
+				continue
+			}
+
+			if r.err == nil {
+				if _, cerr := verifyChecksum(req.GetKey(), r.resp.GetValue().GetValue()); cerr == nil {
+					hedgeWins.With(prometheus.Labels{"client": r.client.Name()}).Inc()
+					return r.resp, true, nil
+				}
+			}
+			if primary != nil {
+				return primary.resp, false, primary.err
+			}
+		}
+	}
+	return primary.resp, false, primary.err
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func hedgedServer(t *testing.T, primaryDelay time.Duration) *Server {
+	primary := getTestBackend("primary")
+	s := getTestServer(primary, getTestBackend("secondary"))
+	h, err := newHedger(95, time.Millisecond*10)
+	if err != nil {
+		t.Fatalf("Bad hedger: %v", err)
+	}
+	s.hedger = h
+	s.Write(context.Background(), &pb.WriteRequest{Key: "things/one", Value: &anypb.Any{Value: []byte("hello")}})
+	primary.readDelay = primaryDelay
+	return s
+}
+
+func TestHedgedRead(t *testing.T) {
+	s := hedgedServer(t, time.Second)
+	ctx := context.Background()
+	start := time.Now()
+	resp, hedged, err := s.readPrimary(ctx, &pb.ReadRequest{Key: "things/one"})
+	if err != nil || !hedged || time.Since(start) > time.Millisecond*500 {
+		t.Errorf("Slow primary was not hedged: %v, %v, %v after %v", resp, hedged, err, time.Since(start))
+	}
+
+	s = hedgedServer(t, 0)
+	if _, hedged, err := s.readPrimary(ctx, &pb.ReadRequest{Key: "things/one"}); err != nil || hedged {
+		t.Errorf("Fast primary was hedged: %v, %v", hedged, err)
+	}
+	if _, hedged, err := s.readPrimary(ctx, &pb.ReadRequest{Key: "things/missing"}); err == nil || hedged {
+		t.Errorf("Missing key was found: %v, %v", hedged, err)
+	}
+}
+
+func TestHedgeDelay(t *testing.T) {
+	h, _ := newHedger(90, time.Millisecond)
+	for i := 1; i <= hedgeSamples; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	if d := h.wait(); d < time.Millisecond*890 || d > time.Millisecond*910 {
+		t.Errorf("Bad hedge delay: %v", d)
+	}
+
+	if _, err := newHedger(150, 0); err == nil {
+		t.Errorf("Bad percentile was accepted")
+	}
+}
+
+func TestHedgeKeepsSlowSamples(t *testing.T) {
+	s := hedgedServer(t, time.Millisecond*100)
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, hedged, err := s.readPrimary(ctx, &pb.ReadRequest{Key: "things/one"}); err != nil || !hedged {
+		t.Fatalf("Slow primary was not hedged: %v, %v", hedged, err)
+	}
+
+	// The caller is done with the read but the primary's time is still wanted
+	cancel()
+	for start := time.Now(); ; time.Sleep(time.Millisecond) {
+		s.hedger.lock.Lock()
+		samples := len(s.hedger.samples)
+		s.hedger.lock.Unlock()
+		if samples == 1 {
+			break
+		}
+		if time.Since(start) > time.Second {
+			t.Fatalf("Slow primary read was never sampled")
+		}
+	}
+	if d := s.hedger.samples[0]; d < time.Millisecond*100 {
+		t.Errorf("Bad sample: %v", d)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Sequences are counters stored under their own prefix so they cannot collide with Count
+const sequencePrefix = reservedPrefix + "sequences/"
+
+// Upper bound on the number of IDs leased in a single call
+const maxIDBlock = 1000000
+
+var (
+	idFailoverGap = flag.Int64("id_failover_gap", maxIDBlock, "IDs to skip past the highest value seen when a sequence is allocated without a backend that may hold a later one")
+)
+
+var (
+	idAllocs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_id_allocations",
+	}, []string{"code"})
+	idAllocated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pstore_ids_allocated",
+	})
+	idGaps = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pstore_id_failover_gaps",
+	})
+)
+
+func (s *Server) AllocateIDs(ctx context.Context, req *pb.AllocateIDsRequest) (*pb.AllocateIDsResponse, error) {
+	resp, err := s.allocateIDs(ctx, req)
+	idAllocs.With(prometheus.Labels{"code": fmt.Sprintf("%v", status.Code(err))}).Inc()
+	if err == nil {
+		idAllocated.Add(float64(resp.GetLast() - resp.GetFirst() + 1))
+	}
+	return resp, err
+}
+
+func (s *Server) allocateIDs(ctx context.Context, req *pb.AllocateIDsRequest) (*pb.AllocateIDsResponse, error) {
+	if req.GetSequence() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "sequence name must be set")
+	}
+	count := req.GetCount()
+	if count < 0 || count > maxIDBlock {
+		return nil, status.Errorf(codes.InvalidArgument, "cannot allocate %v ids, must be between 1 and %v", count, maxIDBlock)
+	}
+	if count == 0 {
+		count = 1
+	}
+
+	ns, err := s.namespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := sequencePrefix + namespacedKey(ns, req.GetSequence())
+	rec := s.newAudit(ctx, "allocate_ids", key, nil)
+	rec.setDelta(count)
+	var resp *pb.AllocateIDsResponse
+	err = s.authorize(ctx, rightWrite, key)
+	if err == nil {
+		resp, err = s.allocateRange(ctx, req.GetSequence(), key, count, rec)
+	}
+	s.finishAudit(rec, err)
+	return resp, err
+}
+
+// unfenced picks out the backends missing from a load of the sequence at key which may hold
+// a later value than this process has seen. Once a skip past a backend's possible values has
+// been stored the backend is fenced, it cannot have moved on while it stays unreachable, so
+// an outage costs one skip rather than one per allocation. Must hold the key lock.
+func (s *Server) unfenced(key string, missing []string) []string {
+	s.keyLock.Lock()
+	defer s.keyLock.Unlock()
+	var result []string
+	for _, name := range missing {
+		if !s.idFences[key][name] {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// fence records that the stored value of the sequence at key is clear of anything the missing
+// backends hold, backends that answered are unfenced again. Must hold the key lock.
+func (s *Server) fence(key string, missing []string) {
+	s.keyLock.Lock()
+	defer s.keyLock.Unlock()
+	if s.idFences == nil {
+		s.idFences = make(map[string]map[string]bool)
+	}
+	fences := make(map[string]bool)
+	for _, name := range missing {
+		fences[name] = true
+	}
+	s.idFences[key] = fences
+}
+
+// allocateRange moves the sequence stored at key on by count
+func (s *Server) allocateRange(ctx context.Context, sequence, key string, count int64, rec *auditRecord) (*pb.AllocateIDsResponse, error) {
+	defer s.lockKey(key)()
+	state, missing, err := s.loadCounter(ctx, key, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// A backend we could not reach may hold a later value than the ones we
+	// read, so jump clear of anything it might have handed out
+	if unfenced := s.unfenced(key, missing); len(unfenced) > 0 {
+		log.Printf("Sequence %v read without %v, skipping %v ids", sequence, unfenced, *idFailoverGap)
+		idGaps.Inc()
+		state.Value += *idFailoverGap
+	}
+
+	first := state.GetValue() + 1
+	state.Value += count
+	if err := s.storeCounter(ctx, key, state, rec); err != nil {
+		return nil, err
+	}
+	s.fence(key, missing)
+
+	return &pb.AllocateIDsResponse{First: first, Last: state.GetValue()}, nil
+}
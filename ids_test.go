@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/brotherlogic/pstore/proto"
+)
+
+func TestAllocateIDs(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"), getTestBackend("secondary"))
+
+	first, err := s.AllocateIDs(context.Background(), &pb.AllocateIDsRequest{Sequence: "users", Count: 10})
+	if err != nil {
+		t.Fatalf("Bad allocation: %v", err)
+	}
+	if first.GetFirst() != 1 || first.GetLast() != 10 {
+		t.Errorf("Bad first block: %v", first)
+	}
+
+	second, err := s.AllocateIDs(context.Background(), &pb.AllocateIDsRequest{Sequence: "users"})
+	if err != nil || second.GetFirst() != 11 || second.GetLast() != 11 {
+		t.Errorf("Bad second block: %v, %v", second, err)
+	}
+
+	// Sequences should not be visible as counters
+	val, err := s.GetCount(context.Background(), &pb.GetCountRequest{Counter: "users"})
+	if err != nil || val.GetCount() != 0 {
+		t.Errorf("Sequence leaked into counters: %v, %v", val, err)
+	}
+}
+
+func TestAllocateIDsAfterRestart(t *testing.T) {
+	primary := getTestBackend("primary")
+	secondary := getTestBackend("secondary")
+	s := getTestServer(primary, secondary)
+
+	block, err := s.AllocateIDs(context.Background(), &pb.AllocateIDsRequest{Sequence: "users", Count: 100})
+	if err != nil {
+		t.Fatalf("Bad allocation: %v", err)
+	}
+
+	// A fresh server with the primary down must not reissue anything
+	primary.down = true
+	s = getTestServer(primary, secondary)
+	next, err := s.AllocateIDs(context.Background(), &pb.AllocateIDsRequest{Sequence: "users"})
+	if err != nil {
+		t.Fatalf("Bad allocation after restart: %v", err)
+	}
+	if next.GetFirst() <= block.GetLast()+*idFailoverGap {
+		t.Errorf("Allocation after failover should have skipped ahead: %v vs %v", next, block)
+	}
+
+	// The outage has already been skipped past, so later allocations carry on from there
+	again, err := s.AllocateIDs(context.Background(), &pb.AllocateIDsRequest{Sequence: "users"})
+	if err != nil || again.GetFirst() != next.GetLast()+1 {
+		t.Errorf("Allocation during the same outage skipped again: %v after %v, %v", again, next, err)
+	}
+
+	// Once the primary is back and repaired a later outage is skipped past afresh
+	primary.down = false
+	s.AllocateIDs(context.Background(), &pb.AllocateIDsRequest{Sequence: "users"})
+	primary.down = true
+	later, err := s.AllocateIDs(context.Background(), &pb.AllocateIDsRequest{Sequence: "users"})
+	if err != nil || later.GetFirst() <= again.GetLast()+*idFailoverGap {
+		t.Errorf("Allocation after a second failover should have skipped ahead: %v after %v, %v", later, again, err)
+	}
+}
+
+func TestIDFailoverGapFlag(t *testing.T) {
+	primary := getTestBackend("primary")
+	secondary := getTestBackend("secondary")
+	s := getTestServer(primary, secondary)
+	old := *idFailoverGap
+	*idFailoverGap = 50
+	defer func() { *idFailoverGap = old }()
+
+	block, _ := s.AllocateIDs(context.Background(), &pb.AllocateIDsRequest{Sequence: "users", Count: 10})
+	primary.down = true
+	s = getTestServer(primary, secondary)
+	next, err := s.AllocateIDs(context.Background(), &pb.AllocateIDsRequest{Sequence: "users"})
+	if err != nil || next.GetFirst() != block.GetLast()+51 {
+		t.Errorf("Skip should be the flag as given: %v after %v, %v", next, block, err)
+	}
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	// Index entries are empty keys of the form <indexPrefix><index>/<escaped value>/<key>
+	indexPrefix = reservedPrefix + "indexes/"
+
+	// The definition each index was last built from
+	indexDefPrefix = reservedPrefix + "index_defs/"
+)
+
+var (
+	indexFlag = flag.String("indexes", "", "Secondary indexes as name=prefix:field, e.g. users_by_state=users/:state,users_by_city=users/:address.city")
+)
+
+var (
+	indexUpdates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_index_updates",
+	}, []string{"index", "code"})
+)
+
+type index struct {
+	name   string
+	prefix string
+	field  string
+
+	// Writes under prefix in flight, and while the index is rebuilt a channel closed once it
+	// is done, which new writes wait on, and one closed once the writers have drained
+	gate     sync.Mutex
+	writers  int
+	building chan struct{}
+	drained  chan struct{}
+}
+
+// enter admits a write under the index's prefix, waiting out a rebuild unless ctx ends first
+func (i *index) enter(ctx context.Context) error {
+	for {
+		i.gate.Lock()
+		building := i.building
+		if building == nil {
+			i.writers++
+			i.gate.Unlock()
+			return nil
+		}
+		i.gate.Unlock()
+
+		select {
+		case <-building:
+		case <-ctx.Done():
+			return status.Errorf(status.FromContextError(ctx.Err()).Code(), "waited for index %v to be rebuilt: %v", i.name, ctx.Err())
+		}
+	}
+}
+
+func (i *index) leave() {
+	i.gate.Lock()
+	defer i.gate.Unlock()
+	i.writers--
+	if i.writers == 0 && i.drained != nil {
+		close(i.drained)
+		i.drained = nil
+	}
+}
+
+// startBuild holds off new writes under the index's prefix and waits for those in flight
+func (i *index) startBuild() {
+	i.gate.Lock()
+	i.building = make(chan struct{})
+	var drained chan struct{}
+	if i.writers > 0 {
+		i.drained = make(chan struct{})
+		drained = i.drained
+	}
+	i.gate.Unlock()
+	if drained != nil {
+		<-drained
+	}
+}
+
+func (i *index) endBuild() {
+	i.gate.Lock()
+	defer i.gate.Unlock()
+	close(i.building)
+	i.building = nil
+}
+
+func parseIndexes(flagValue string) ([]*index, error) {
+	var result []*index
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(flagValue, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		nameDef := strings.SplitN(entry, "=", 2)
+		if len(nameDef) != 2 {
+			return nil, fmt.Errorf("bad index %q", entry)
+		}
+		prefixField := strings.SplitN(nameDef[1], ":", 2)
+		if len(prefixField) != 2 || nameDef[0] == "" || prefixField[1] == "" || strings.Contains(nameDef[0], "/") {
+			return nil, fmt.Errorf("bad index %q", entry)
+		}
+		if seen[nameDef[0]] {
+			return nil, fmt.Errorf("index %v is defined twice", nameDef[0])
+		}
+		seen[nameDef[0]] = true
+		result = append(result, &index{name: nameDef[0], prefix: prefixField[0], field: prefixField[1]})
+	}
+	return result, nil
+}
+
+func (i *index) entryPrefix(value string) string {
+	return indexPrefix + i.name + "/" + url.PathEscape(value) + "/"
+}
+
+// indexesFor returns the indexes which cover key
+func (s *Server) indexesFor(key string) []*index {
+	if strings.HasPrefix(key, reservedPrefix) {
+		return nil
+	}
+	var result []*index
+	for _, i := range s.indexes {
+		if strings.HasPrefix(key, i.prefix) {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// lockIndexes holds off rebuilds of indexes while a key they cover is changed. It is
+// taken before the key lock, so a write waiting out a rebuild holds nothing else up.
+func lockIndexes(ctx context.Context, indexes []*index) (func(), error) {
+	for n, i := range indexes {
+		if err := i.enter(ctx); err != nil {
+			for _, entered := range indexes[:n] {
+				entered.leave()
+			}
+			return nil, err
+		}
+	}
+	return func() {
+		for _, i := range indexes {
+			i.leave()
+		}
+	}, nil
+}
+
+// fieldValues walks a dotted field path through msg and returns the string form
+// of every value found there, repeated fields give one value per element
+func fieldValues(msg protoreflect.Message, path string) ([]string, error) {
+	fd, values, err := lookupField(msg, path)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, v := range values {
+		result = append(result, scalarString(fd, v))
+	}
+	return result, nil
+}
+
+// lookupField walks a dotted path to a scalar field, returning every value there
+func lookupField(msg protoreflect.Message, path string) (protoreflect.FieldDescriptor, []protoreflect.Value, error) {
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		fd := msg.Descriptor().Fields().ByName(protoreflect.Name(part))
+		if fd == nil {
+			return nil, nil, fmt.Errorf("%v has no field %v", msg.Descriptor().FullName(), part)
+		}
+
+		if i < len(parts)-1 {
+			if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+				return nil, nil, fmt.Errorf("cannot walk through %v", fd.FullName())
+			}
+			msg = msg.Get(fd).Message()
+			continue
+		}
+
+		if fd.IsMap() || fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+			return nil, nil, fmt.Errorf("%v is not a scalar field", fd.FullName())
+		}
+		if fd.IsList() {
+			var values []protoreflect.Value
+			list := msg.Get(fd).List()
+			for j := 0; j < list.Len(); j++ {
+				values = append(values, list.Get(j))
+			}
+			return fd, values, nil
+		}
+		return fd, []protoreflect.Value{msg.Get(fd)}, nil
+	}
+	return nil, nil, fmt.Errorf("empty field path")
+}
+
+func scalarString(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	if fd.Kind() == protoreflect.EnumKind {
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+	}
+	if fd.Kind() == protoreflect.BytesKind {
+		return string(v.Bytes())
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
+
+// indexValues returns the values of value for index i, values of unknown types give nothing
+func (s *Server) indexValues(i *index, value *anypb.Any) []string {
+	if value == nil {
+		return nil
+	}
+	msg, err := s.registry.unpack(value)
+	if err != nil {
+		return nil
+	}
+	values, err := fieldValues(msg.ProtoReflect(), i.field)
+	if err != nil {
+		log.Printf("Unable to index %v: %v", i.name, err)
+		indexUpdates.With(prometheus.Labels{"index": i.name, "code": "bad_field"}).Inc()
+		return nil
+	}
+	return values
+}
+
+// updateIndexes moves the entries for key from the old value to the new one, either can be nil
+func (s *Server) updateIndexes(ctx context.Context, key string, indexes []*index, old, nvalue *anypb.Any) {
+	for _, i := range indexes {
+		oldValues := make(map[string]bool)
+		for _, v := range s.indexValues(i, old) {
+			oldValues[v] = true
+		}
+		newValues := make(map[string]bool)
+		for _, v := range s.indexValues(i, nvalue) {
+			newValues[v] = true
+		}
+
+		for v := range oldValues {
+			if !newValues[v] {
+				_, err := s.deleteAll(ctx, &pb.DeleteRequest{Key: i.entryPrefix(v) + key}, nil)
+				indexUpdates.With(prometheus.Labels{"index": i.name, "code": fmt.Sprintf("%v", status.Code(err))}).Inc()
+			}
+		}
+		for v := range newValues {
+			if !oldValues[v] {
+				_, err := s.writeIndexEntry(ctx, i.entryPrefix(v)+key)
+				indexUpdates.With(prometheus.Labels{"index": i.name, "code": fmt.Sprintf("%v", status.Code(err))}).Inc()
+			}
+		}
+	}
+}
+
+func (s *Server) writeIndexEntry(ctx context.Context, entry string) (*pb.WriteResponse, error) {
+	value, err := s.encodeValue(entry, &anypb.Any{})
+	if err != nil {
+		return nil, err
+	}
+	return s.writeAll(ctx, &pb.WriteRequest{Key: entry, Value: &anypb.Any{Value: value}}, nil)
+}
+
+// previousValue reads the value being replaced so its index entries can be removed
+func (s *Server) previousValue(ctx context.Context, key string, indexes []*index) *anypb.Any {
+	if len(indexes) == 0 {
+		return nil
+	}
+	resp, err := s.read(ctx, &pb.ReadRequest{Key: key})
+	if err != nil {
+		return nil
+	}
+	return resp.GetValue()
+}
+
+func (s *Server) QueryIndex(ctx context.Context, req *pb.QueryIndexRequest) (*pb.QueryIndexResponse, error) {
+	// Indexes only cover the shared key space
+	if ns, err := s.namespace(ctx); err != nil || ns != "" {
+		if err == nil {
+			err = status.Errorf(codes.FailedPrecondition, "indexes are not available in namespace %v", ns)
+		}
+		return nil, err
+	}
+
+	var found *index
+	for _, i := range s.indexes {
+		if i.name == req.GetIndex() {
+			found = i
+		}
+	}
+	if found == nil {
+		return nil, status.Errorf(codes.NotFound, "no index called %q", req.GetIndex())
+	}
+
+	if err := s.authorize(ctx, rightList, found.prefix); err != nil {
+		return nil, err
+	}
+
+	entryPrefix := found.entryPrefix(req.GetValue())
+	resp, err := s.getKeys(ctx, &pb.GetKeysRequest{Prefix: entryPrefix})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &pb.QueryIndexResponse{}
+	for _, key := range resp.GetKeys() {
+		result.Keys = append(result.Keys, strings.TrimPrefix(key, entryPrefix))
+	}
+	return result, nil
+}
+
+// buildIndexes rebuilds any index whose definition has changed since it was last built
+func (s *Server) buildIndexes(ctx context.Context) error {
+	for _, i := range s.indexes {
+		if err := s.buildIndex(ctx, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildIndex rebuilds i if its definition has changed, writes under its prefix wait until it is done
+func (s *Server) buildIndex(ctx context.Context, i *index) error {
+	def := &pb.IndexDefinition{Prefix: i.prefix, Field: i.field}
+	resp, err := s.read(ctx, &pb.ReadRequest{Key: indexDefPrefix + i.name})
+	if err == nil {
+		stored := &pb.IndexDefinition{}
+		if resp.GetValue().UnmarshalTo(stored) == nil && proto.Equal(stored, def) {
+			return nil
+		}
+	} else if status.Code(err) != codes.NotFound {
+		return err
+	}
+
+	i.startBuild()
+	defer i.endBuild()
+
+	log.Printf("Building index %v over %v", i.name, i.prefix)
+	entries, err := s.getKeys(ctx, &pb.GetKeysRequest{Prefix: indexPrefix + i.name + "/"})
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries.GetKeys() {
+		if _, err := s.deleteAll(ctx, &pb.DeleteRequest{Key: entry}, nil); err != nil {
+			return err
+		}
+	}
+
+	keys, err := s.getKeys(ctx, &pb.GetKeysRequest{Prefix: i.prefix, AllKeys: i.prefix == ""})
+	if err != nil {
+		return err
+	}
+	for _, key := range keys.GetKeys() {
+		if strings.HasPrefix(key, reservedPrefix) {
+			continue
+		}
+		value, err := s.read(ctx, &pb.ReadRequest{Key: key})
+		if err != nil {
+			log.Printf("Unable to index %v: %v", key, err)
+			continue
+		}
+		s.updateIndexes(ctx, key, []*index{i}, nil, value.GetValue())
+	}
+
+	value, err := anypb.New(def)
+	if err != nil {
+		return err
+	}
+	_, err = s.write(ctx, "", &pb.WriteRequest{Key: indexDefPrefix + i.name, Value: value}, nil)
+	return err
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func queryIndex(t *testing.T, s *Server, index, value string) string {
+	resp, err := s.QueryIndex(context.Background(), &pb.QueryIndexRequest{Index: index, Value: value})
+	if err != nil {
+		t.Fatalf("Bad query: %v", err)
+	}
+	keys := resp.GetKeys()
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+func TestIndexes(t *testing.T) {
+	primary := getTestBackend("primary")
+	s := getTestServer(primary)
+	s.registerDescriptors(context.Background(), testDescriptors())
+	indexes, err := parseIndexes("by_size=things/:size,by_name=things/:name")
+	if err != nil {
+		t.Fatalf("Bad indexes: %v", err)
+	}
+	s.indexes = indexes
+
+	s.Write(context.Background(), &pb.WriteRequest{Key: "things/one", Value: thing("one", 5)})
+	s.Write(context.Background(), &pb.WriteRequest{Key: "things/two", Value: thing("two", 5)})
+	s.Write(context.Background(), &pb.WriteRequest{Key: "things/a/b", Value: thing("a/b", 6)})
+
+	if got := queryIndex(t, s, "by_size", "5"); got != "things/one,things/two" {
+		t.Errorf("Bad size query: %v", got)
+	}
+	if got := queryIndex(t, s, "by_name", "a/b"); got != "things/a/b" {
+		t.Errorf("Values with slashes should be indexed: %v", got)
+	}
+
+	// Changing a value moves it in the index
+	s.Write(context.Background(), &pb.WriteRequest{Key: "things/two", Value: thing("two", 6)})
+	if got := queryIndex(t, s, "by_size", "5"); got != "things/one" {
+		t.Errorf("Old entry was not removed: %v", got)
+	}
+	if got := queryIndex(t, s, "by_size", "6"); got != "things/a/b,things/two" {
+		t.Errorf("New entry was not added: %v", got)
+	}
+
+	s.Delete(context.Background(), &pb.DeleteRequest{Key: "things/one"})
+	if got := queryIndex(t, s, "by_size", "5"); got != "" {
+		t.Errorf("Deleted key is still indexed: %v", got)
+	}
+
+	if _, err := s.QueryIndex(context.Background(), &pb.QueryIndexRequest{Index: "missing"}); err == nil {
+		t.Errorf("Unknown index should fail")
+	}
+}
+
+func TestIndexBackfill(t *testing.T) {
+	primary := getTestBackend("primary")
+	s := getTestServer(primary)
+	s.registerDescriptors(context.Background(), testDescriptors())
+
+	s.Write(context.Background(), &pb.WriteRequest{Key: "things/one", Value: thing("one", 5)})
+	s.Write(context.Background(), &pb.WriteRequest{Key: "things/two", Value: thing("two", 7)})
+
+	s.indexes, _ = parseIndexes("by_size=things/:size")
+	if err := s.buildIndexes(context.Background()); err != nil {
+		t.Fatalf("Bad build: %v", err)
+	}
+	if got := queryIndex(t, s, "by_size", "7"); got != "things/two" {
+		t.Errorf("Existing values were not indexed: %v", got)
+	}
+
+	// Redefining the index throws away the old entries
+	s.indexes, _ = parseIndexes("by_size=things/:name")
+	if err := s.buildIndexes(context.Background()); err != nil {
+		t.Fatalf("Bad rebuild: %v", err)
+	}
+	if got := queryIndex(t, s, "by_size", "7"); got != "" {
+		t.Errorf("Stale entries survived the rebuild: %v", got)
+	}
+	if got := queryIndex(t, s, "by_size", "one"); got != "things/one" {
+		t.Errorf("Rebuild did not index: %v", got)
+	}
+}
+
+func TestIndexConcurrentWrites(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+	s.registerDescriptors(context.Background(), testDescriptors())
+	s.indexes, _ = parseIndexes("by_size=things/:size")
+
+	waitgroup := &sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		waitgroup.Add(1)
+		go func() {
+			defer waitgroup.Done()
+			s.Write(context.Background(), &pb.WriteRequest{Key: "things/one", Value: thing("one", int32(i+1))})
+		}()
+	}
+	waitgroup.Wait()
+
+	entries, err := s.getKeys(context.Background(), &pb.GetKeysRequest{Prefix: indexPrefix})
+	if err != nil || len(entries.GetKeys()) != 1 {
+		t.Errorf("Racing writes left stale index entries: %v, %v", entries, err)
+	}
+}
+
+func TestWritesWaitOutRebuilds(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+	s.registerDescriptors(context.Background(), testDescriptors())
+	s.indexes, _ = parseIndexes("by_size=things/:size")
+
+	s.indexes[0].startBuild()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	if _, err := s.Write(ctx, &pb.WriteRequest{Key: "things/one", Value: thing("one", 1)}); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Write during a rebuild should give up at its deadline: %v", err)
+	}
+	if _, err := s.Write(context.Background(), &pb.WriteRequest{Key: "other/one", Value: thing("one", 1)}); err != nil {
+		t.Errorf("Write outside the index should not wait: %v", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := s.Write(context.Background(), &pb.WriteRequest{Key: "things/one", Value: thing("one", 2)})
+		done <- err
+	}()
+	time.Sleep(time.Millisecond * 20)
+
+	// The waiting write holds no key lock, so pstore can still work on the key
+	unlock := s.lockKey("things/one")
+	unlock()
+
+	s.indexes[0].endBuild()
+	if err := <-done; err != nil {
+		t.Errorf("Write was not let through after the rebuild: %v", err)
+	}
+	if got := queryIndex(t, s, "by_size", "2"); got != "things/one" {
+		t.Errorf("Write after the rebuild was not indexed: %v", got)
+	}
+}
+
+func TestParseIndexes(t *testing.T) {
+	for _, bad := range []string{"noprefix", "a=b", "a/b=c:d", "a=p:f,a=p:g"} {
+		if _, err := parseIndexes(bad); err == nil {
+			t.Errorf("%v should not parse", bad)
+		}
+	}
+}
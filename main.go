@@ -21,6 +21,8 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 var (
@@ -92,6 +94,54 @@ type Server struct {
 	clients []pstore
 
 	wq chan *WriteElement
+
+	compression *compressionConfig
+	keyring     *keyring
+
+	typeBindings map[string]string
+	registry     *descriptorRegistry
+	indexes      []*index
+
+	keyLock      sync.Mutex
+	keyLocks     map[string]*keyMutex
+	counterMarks map[string]*pb.CounterState
+	idFences     map[string]map[string]bool
+
+	namespaceLimits map[string]*namespaceLimits
+	nsLock          sync.Mutex
+	nsUsage         map[string]*namespaceUsage
+
+	tokens      map[[32]byte]string
+	requireAuth bool
+	uiSecret    []byte
+
+	aclLock sync.RWMutex
+	acl     *aclPolicy
+
+	limiter     *limiter
+	latencyLock sync.Mutex
+	latency     float64
+
+	audit *auditLog
+	cache *readCache
+
+	hedger   *hedger
+	breakers *breakers
+	reads    coalescer[*pb.ReadResponse]
+	listings coalescer[*pb.GetKeysResponse]
+
+	changelog  bool
+	changeLock sync.Mutex
+	changeNext int64
+	changeLast int64
+	changeLost int64
+	pending    map[int64]*pendingChange
+	gaps       map[int64]*pb.ChangeEvent
+	followers  map[chan *pb.ChangeEvent]bool
+
+	snapshotDir string
+
+	trashRetention time.Duration
 }
 
 type pstore interface {
@@ -118,8 +168,13 @@ func (s *Server) split(ctx context.Context, arlen int) int {
 }
 
 func (s *Server) runRead(ctx context.Context, client pstore, req *pb.ReadRequest) (*pb.ReadResponse, error) {
+	if err := s.checkBackend(client); err != nil {
+		return nil, err
+	}
 	t := time.Now()
 	resp, err := client.Read(ctx, req)
+	s.recordBackend(ctx, client, time.Since(t), err)
+	s.observeLatency(time.Since(t))
 	rCount.With(prometheus.Labels{"client": client.Name(), "code": fmt.Sprintf("%v", status.Code(err))}).Inc()
 	if err == nil {
 		rCountTime.With(prometheus.Labels{"client": client.Name()}).Observe(float64(time.Since(t).Milliseconds()))
@@ -130,9 +185,48 @@ func (s *Server) runRead(ctx context.Context, client pstore, req *pb.ReadRequest
 }
 
 func (s *Server) Read(ctx context.Context, req *pb.ReadRequest) (*pb.ReadResponse, error) {
-	log.Printf("Read %v", req.GetKey())
+	if err := checkCallerKey(req.GetKey()); err != nil {
+		return nil, err
+	}
+	ns, err := s.namespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key := namespacedKey(ns, req.GetKey())
+	if err := s.authorize(ctx, rightRead, key); err != nil {
+		return nil, err
+	}
+	return s.read(ctx, &pb.ReadRequest{Key: key, RenderJson: req.GetRenderJson()})
+}
+
+// read reads a key as it is stored, outside of any namespace
+func (s *Server) read(ctx context.Context, req *pb.ReadRequest) (*pb.ReadResponse, error) {
+	log.Printf("Read %v (%v)", req.GetKey(), callerFrom(ctx))
 	defer log.Printf("Finished Read %v", req.GetKey())
-	mResp, merr := s.runRead(ctx, s.clients[0], req)
+	resp, ok := s.cache.get(req.GetKey())
+	if !ok {
+		var err error
+		resp, err = s.reads.do(ctx, "read", req.GetKey(), func(ctx context.Context) (*pb.ReadResponse, error) {
+			return s.fetch(ctx, req.GetKey())
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.renderJSON(req, resp)
+	return resp, nil
+}
+
+// fetch reads key from the primary, checking it against the other backends and repairing them
+func (s *Server) fetch(ctx context.Context, key string) (*pb.ReadResponse, error) {
+	req := &pb.ReadRequest{Key: key}
+	version := s.cache.version(key)
+	mResp, hedged, merr := s.readPrimary(ctx, req)
+	if merr == nil && !hedged {
+		if _, cerr := verifyChecksum(req.GetKey(), mResp.GetValue().GetValue()); cerr != nil {
+			mResp, merr = s.healthyRead(ctx, req, cerr)
+		}
+	}
 
 	deadline, ok := ctx.Deadline()
 	timeout := time.Minute
@@ -141,19 +235,26 @@ func (s *Server) Read(ctx context.Context, req *pb.ReadRequest) (*pb.ReadRespons
 	}
 	oCtx, cancel := context.WithTimeout(context.Background(), timeout)
 	waitgroup := &sync.WaitGroup{}
-	if merr == nil {
+
+	// A hedged answer came from a secondary, so it is no yardstick for the others
+	if merr == nil && !hedged {
 		for _, c := range s.clients[1:] {
 			waitgroup.Add(1)
 			go func() {
 				resp, err := s.runRead(oCtx, c, req)
 				if err == nil {
-					if len(resp.GetValue().GetValue()) != len(mResp.GetValue().GetValue()) {
+					_, cerr := verifyChecksum(req.GetKey(), resp.GetValue().GetValue())
+					if cerr != nil {
+						log.Printf("Corrupt value for %v on %v: %v", req.GetKey(), c.Name(), cerr)
+						corruptionCount.With(prometheus.Labels{"client": c.Name()}).Inc()
+					}
+					if cerr != nil || len(resp.GetValue().GetValue()) != len(mResp.GetValue().GetValue()) {
 						log.Printf("READ Miss: %v => %v vs %v", req.GetKey(), len(string((resp.GetValue().GetValue()))), len(string(mResp.GetValue().GetValue())))
 
 						// Since we missed, do a re-write
 						s.wq <- &WriteElement{
 							key:   req.GetKey(),
-							value: mResp.GetValue().GetValue(),
+							value: mResp.GetValue(),
 							cname: c.Name(),
 						}
 
@@ -162,7 +263,7 @@ func (s *Server) Read(ctx context.Context, req *pb.ReadRequest) (*pb.ReadRespons
 				} else if status.Code(err) == codes.NotFound {
 					s.wq <- &WriteElement{
 						key:   req.GetKey(),
-						value: mResp.GetValue().GetValue(),
+						value: mResp.GetValue(),
 						cname: c.Name(),
 					}
 				}
@@ -179,12 +280,43 @@ func (s *Server) Read(ctx context.Context, req *pb.ReadRequest) (*pb.ReadRespons
 		cancel()
 	}()
 
-	return mResp, merr
+	if merr != nil {
+		return mResp, merr
+	}
+
+	value, err := s.decodeValue(req.GetKey(), mResp.GetValue())
+	if err != nil {
+		return nil, status.Errorf(codes.DataLoss, "unable to decode %v: %v", req.GetKey(), err)
+	}
+	resp := &pb.ReadResponse{
+		Value:     value,
+		Timestamp: mResp.GetTimestamp(),
+	}
+	if !hedged {
+		s.cache.put(req.GetKey(), resp, version)
+	}
+	return resp, nil
+}
+
+func (s *Server) renderJSON(req *pb.ReadRequest, resp *pb.ReadResponse) {
+	if !req.GetRenderJson() {
+		return
+	}
+	var err error
+	resp.Json, err = s.registry.renderJSON(resp.GetValue())
+	if err != nil {
+		log.Printf("Unable to render %v as JSON: %v", req.GetKey(), err)
+	}
 }
 
 func (s *Server) runWrite(ctx context.Context, client pstore, req *pb.WriteRequest) (*pb.WriteResponse, error) {
+	if err := s.checkBackend(client); err != nil {
+		return nil, err
+	}
 	t := time.Now()
 	resp, err := client.Write(ctx, req)
+	s.recordBackend(ctx, client, time.Since(t), err)
+	s.observeLatency(time.Since(t))
 	wCount.With(prometheus.Labels{"client": client.Name(), "code": fmt.Sprintf("%v", status.Code(err))}).Inc()
 	if err == nil {
 		log.Printf("Write %v -> %v (%v)", req.GetKey(), client.Name(), time.Since(t))
@@ -196,8 +328,94 @@ func (s *Server) runWrite(ctx context.Context, client pstore, req *pb.WriteReque
 }
 
 func (s *Server) Write(ctx context.Context, req *pb.WriteRequest) (*pb.WriteResponse, error) {
-	log.Printf("Write %v", req.GetKey())
+	if err := checkCallerKey(req.GetKey()); err != nil {
+		return nil, err
+	}
+	ns, err := s.namespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key := namespacedKey(ns, req.GetKey())
+	rec := s.newAudit(ctx, "write", key, req.GetValue().GetValue())
+	var resp *pb.WriteResponse
+	err = s.authorize(ctx, rightWrite, key)
+	if err == nil {
+		resp, err = s.write(ctx, ns, &pb.WriteRequest{Key: key, Value: req.GetValue()}, rec)
+	}
+	s.finishAudit(rec, err)
+	return resp, err
+}
+
+// write stores a value under its stored key, charging it to ns
+func (s *Server) write(ctx context.Context, ns string, req *pb.WriteRequest, rec *auditRecord) (*pb.WriteResponse, error) {
+	log.Printf("Write %v (%v)", req.GetKey(), callerFrom(ctx))
 	defer log.Printf("Finished write %v", req.GetKey())
+
+	value, err := s.encodeWrite(req)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := s.indexesFor(req.GetKey())
+	unlockIndexes, err := lockIndexes(ctx, indexes)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockIndexes()
+
+	// Held until the change is recorded, so the events for one key are in the order they were applied
+	defer s.lockKey(req.GetKey())()
+	return s.writeLocked(ctx, ns, req, value, indexes, rec)
+}
+
+// encodeWrite checks a value is fit to be written and encodes it for the backends
+func (s *Server) encodeWrite(req *pb.WriteRequest) ([]byte, error) {
+	if err := s.checkType(req.GetKey(), req.GetValue()); err != nil {
+		return nil, err
+	}
+	if err := s.registry.validate(req.GetValue()); err != nil {
+		return nil, err
+	}
+
+	value, err := s.encodeValue(req.GetKey(), req.GetValue())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to encode %v: %v", req.GetKey(), err)
+	}
+	return value, nil
+}
+
+// writeLocked stores the value encoded by encodeWrite. Must hold the gate of every
+// index on the key and the key lock.
+func (s *Server) writeLocked(ctx context.Context, ns string, req *pb.WriteRequest, value []byte, indexes []*index, rec *auditRecord) (*pb.WriteResponse, error) {
+	if err := s.checkPreconditions(ctx, req.GetKey()); err != nil {
+		return nil, err
+	}
+
+	var dkeys, dbytes int64
+	var err error
+	if ns != "" {
+		dkeys, dbytes, err = s.checkQuota(ctx, ns, req.GetKey(), len(req.GetValue().GetValue()), len(value))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	old := s.previousValue(ctx, req.GetKey(), indexes)
+
+	resp, err := s.writeAll(ctx, &pb.WriteRequest{
+		Key:   req.GetKey(),
+		Value: &anypb.Any{TypeUrl: req.GetValue().GetTypeUrl(), Value: value},
+	}, rec)
+	if err == nil {
+		s.updateIndexes(ctx, req.GetKey(), indexes, old, req.GetValue())
+		s.addUsage(ns, req.GetKey(), dkeys, dbytes)
+		s.recordChange(ctx, pb.ChangeEvent_WRITE, req.GetKey(), req.GetValue())
+	}
+	return resp, err
+}
+
+// writeAll writes an already encoded value to the primary and then every other backend
+func (s *Server) writeAll(ctx context.Context, req *pb.WriteRequest, rec *auditRecord) (*pb.WriteResponse, error) {
 	t := time.Now()
 	deadline, ok := ctx.Deadline()
 	timeout := time.Minute
@@ -208,12 +426,19 @@ func (s *Server) Write(ctx context.Context, req *pb.WriteRequest) (*pb.WriteResp
 	waitgroup := &sync.WaitGroup{}
 
 	mresp, err := s.runWrite(ctx, s.clients[0], req)
+	s.invalidate(req.GetKey())
+	rec.backend(s.clients[0].Name(), err)
 
 	if err == nil {
 		for _, c := range s.clients[1:] {
 			waitgroup.Add(1)
+			rec.expect()
 			go func() {
-				s.runWrite(oCtx, c, req)
+				_, werr := s.runWrite(oCtx, c, req)
+				if werr != nil && s.breakers.isOpen(c.Name()) {
+					s.breakers.hold(c.Name(), &WriteElement{key: req.GetKey(), value: req.GetValue(), cname: c.Name()})
+				}
+				rec.answer(c.Name(), werr)
 				waitgroup.Done()
 			}()
 		}
@@ -229,8 +454,13 @@ func (s *Server) Write(ctx context.Context, req *pb.WriteRequest) (*pb.WriteResp
 }
 
 func (s *Server) runGetKeys(ctx context.Context, client pstore, req *pb.GetKeysRequest) (*pb.GetKeysResponse, error) {
+	if err := s.checkBackend(client); err != nil {
+		return nil, err
+	}
 	t := time.Now()
 	resp, err := client.GetKeys(ctx, req)
+	s.recordBackend(ctx, client, time.Since(t), err)
+	s.observeLatency(time.Since(t))
 	gkCount.With(prometheus.Labels{"client": client.Name(), "code": fmt.Sprintf("%v", status.Code(err))}).Inc()
 	if err == nil {
 		gkCountTime.With(prometheus.Labels{"client": client.Name()}).Observe(float64(time.Since(t).Milliseconds()))
@@ -239,6 +469,50 @@ func (s *Server) runGetKeys(ctx context.Context, client pstore, req *pb.GetKeysR
 }
 
 func (s *Server) GetKeys(ctx context.Context, req *pb.GetKeysRequest) (*pb.GetKeysResponse, error) {
+	if !req.GetAllKeys() {
+		if err := checkCallerKey(req.GetPrefix()); err != nil {
+			return nil, err
+		}
+	}
+	ns, err := s.namespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefix := namespacedKey(ns, req.GetPrefix())
+	if req.GetAllKeys() && ns == "" {
+		prefix = ""
+	}
+	filter, err := s.authorizeList(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if ns != "" {
+		req = &pb.GetKeysRequest{Prefix: prefix, AvoidSuffix: req.GetAvoidSuffix()}
+	}
+	resp, err := s.getKeys(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	keys := resp.GetKeys()
+	if ns == "" {
+		keys = dropReserved(keys)
+	}
+	return &pb.GetKeysResponse{Keys: stripNamespace(ns, filter(keys))}, nil
+}
+
+func (s *Server) getKeys(ctx context.Context, req *pb.GetKeysRequest) (*pb.GetKeysResponse, error) {
+	id, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	return s.listings.do(ctx, "get_keys", string(id), func(ctx context.Context) (*pb.GetKeysResponse, error) {
+		return s.listKeys(ctx, req)
+	})
+}
+
+// listKeys lists keys on the primary, comparing the listing with the other backends
+func (s *Server) listKeys(ctx context.Context, req *pb.GetKeysRequest) (*pb.GetKeysResponse, error) {
 	log.Printf("GetKeys %v", req)
 	defer log.Printf("Finished GetKeys %v", req)
 	deadline, ok := ctx.Deadline()
@@ -282,8 +556,13 @@ func (s *Server) GetKeys(ctx context.Context, req *pb.GetKeysRequest) (*pb.GetKe
 }
 
 func (s *Server) runDelete(ctx context.Context, client pstore, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	if err := s.checkBackend(client); err != nil {
+		return nil, err
+	}
 	t := time.Now()
 	resp, err := client.Delete(ctx, req)
+	s.recordBackend(ctx, client, time.Since(t), err)
+	s.observeLatency(time.Since(t))
 	dCount.With(prometheus.Labels{"client": client.Name(), "code": fmt.Sprintf("%v", status.Code(err))}).Inc()
 	if err == nil {
 		dCountTime.With(prometheus.Labels{"client": client.Name()}).Observe(float64(time.Since(t).Milliseconds()))
@@ -292,6 +571,66 @@ func (s *Server) runDelete(ctx context.Context, client pstore, req *pb.DeleteReq
 }
 
 func (s *Server) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	if err := checkCallerKey(req.GetKey()); err != nil {
+		return nil, err
+	}
+	ns, err := s.namespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req = &pb.DeleteRequest{Key: namespacedKey(ns, req.GetKey()), Hard: req.GetHard()}
+	rec := s.newAudit(ctx, "delete", req.GetKey(), nil)
+	resp, err := s.delete(ctx, ns, req, rec)
+	s.finishAudit(rec, err)
+	return resp, err
+}
+
+func (s *Server) delete(ctx context.Context, ns string, req *pb.DeleteRequest, rec *auditRecord) (*pb.DeleteResponse, error) {
+	if err := s.authorize(ctx, rightDelete, req.GetKey()); err != nil {
+		return nil, err
+	}
+
+	indexes := s.indexesFor(req.GetKey())
+	unlockIndexes, err := lockIndexes(ctx, indexes)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockIndexes()
+
+	defer s.lockKey(req.GetKey())()
+	if err := s.checkPreconditions(ctx, req.GetKey()); err != nil {
+		return nil, err
+	}
+
+	size := int64(-1)
+	if ns != "" {
+		size, err = s.storedSize(ctx, req.GetKey())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if s.trashRetention > 0 && !req.GetHard() && trashable(req.GetKey()) {
+		if err := s.moveToTrash(ctx, req.GetKey()); err != nil {
+			return nil, err
+		}
+	}
+
+	old := s.previousValue(ctx, req.GetKey(), indexes)
+
+	resp, err := s.deleteAll(ctx, req, rec)
+	if err == nil {
+		s.updateIndexes(ctx, req.GetKey(), indexes, old, nil)
+		if size >= 0 {
+			s.addUsage(ns, req.GetKey(), -1, -size)
+		}
+		s.recordChange(ctx, pb.ChangeEvent_DELETE, req.GetKey(), nil)
+	}
+	return resp, err
+}
+
+// deleteAll removes a key from the primary and then every other backend
+func (s *Server) deleteAll(ctx context.Context, req *pb.DeleteRequest, rec *auditRecord) (*pb.DeleteResponse, error) {
 	deadline, ok := ctx.Deadline()
 	timeout := time.Minute
 	if ok {
@@ -305,12 +644,19 @@ func (s *Server) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteR
 	}()
 
 	mresp, err := s.runDelete(ctx, s.clients[0], req)
+	s.invalidate(req.GetKey())
+	rec.backend(s.clients[0].Name(), err)
 
 	if err == nil {
 		for _, c := range s.clients[1:] {
 			waitgroup.Add(1)
+			rec.expect()
 			go func() {
 				_, terr := s.runDelete(oCtx, c, req)
+				if terr != nil && s.breakers.isOpen(c.Name()) {
+					s.breakers.hold(c.Name(), &WriteElement{key: req.GetKey(), cname: c.Name(), delete: true})
+				}
+				rec.answer(c.Name(), terr)
 				if status.Code(terr) != status.Code(err) {
 					gkCountDiffs.Inc()
 				}
@@ -332,8 +678,12 @@ func (s *Server) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteR
 }
 
 func (s *Server) runCount(ctx context.Context, client pstore, req *pb.CountRequest) (*pb.CountResponse, error) {
+	if err := s.checkBackend(client); err != nil {
+		return nil, err
+	}
 	t := time.Now()
 	resp, err := client.Count(ctx, req)
+	s.recordBackend(ctx, client, time.Since(t), err)
 	cCount.With(prometheus.Labels{"client": client.Name(), "code": fmt.Sprintf("%v", status.Code(err))}).Inc()
 	if err == nil {
 		cCountTime.With(prometheus.Labels{"client": client.Name()}).Observe(float64(time.Since(t).Milliseconds()))
@@ -341,33 +691,108 @@ func (s *Server) runCount(ctx context.Context, client pstore, req *pb.CountReque
 	return resp, err
 }
 
-func (s *Server) Count(ctx context.Context, req *pb.CountRequest) (*pb.CountResponse, error) {
-	mresp, err := s.runCount(ctx, s.clients[0], req)
+func main() {
+	flag.Parse()
 
-	if err == nil {
-		for _, c := range s.clients {
-			go func() {
-				resp, err := s.runCount(ctx, c, req)
-				if err == nil {
-					if resp.GetCount() != mresp.GetCount() {
-						cCountDiffs.Inc()
-					}
-				}
-			}()
+	s := &Server{
+		wq:       make(chan *WriteElement, 100),
+		registry: newDescriptorRegistry(),
+	}
+
+	cconfig, err := parseCompression(*compression, *compressionThreshold, *compressionPrefixes)
+	if err != nil {
+		log.Fatalf("Bad compression config: %v", err)
+	}
+	s.compression = cconfig
+
+	bindings, err := parseTypeBindings(*typeBindings)
+	if err != nil {
+		log.Fatalf("Bad type bindings: %v", err)
+	}
+	s.typeBindings = bindings
+
+	cache, err := parseCache(*cacheBytes, *cacheTTL, *cacheTTLs)
+	if err != nil {
+		log.Fatalf("Bad cache config: %v", err)
+	}
+	s.cache = cache
+
+	hedger, err := newHedger(*hedgePercentile, *hedgeMinDelay)
+	if err != nil {
+		log.Fatalf("Bad hedging config: %v", err)
+	}
+	s.hedger = hedger
+	s.breakers = newBreakers(*breakerErrorRate, *breakerLatency, *breakerWindow, *breakerCooldown, *breakerMaxRepairs)
+
+	indexes, err := parseIndexes(*indexFlag)
+	if err != nil {
+		log.Fatalf("Bad indexes: %v", err)
+	}
+	s.indexes = indexes
+
+	if *namespaceFile != "" {
+		limits, err := loadNamespaces(*namespaceFile)
+		if err != nil {
+			log.Fatalf("Unable to load namespaces: %v", err)
 		}
+		s.namespaceLimits = limits
 	}
 
-	return mresp, err
-}
+	if *auditFile != "" {
+		audit, err := openAuditLog(*auditFile, *auditMaxBytes, *auditFiles)
+		if err != nil {
+			log.Fatalf("Unable to open audit log: %v", err)
+		}
+		s.audit = audit
+	}
 
-func main() {
-	flag.Parse()
+	if *changelogEnabled {
+		s.changelog = true
+		go s.runChangelogPurge()
+	}
 
-	s := &Server{
-		wq: make(chan *WriteElement, 100),
+	if *trashRetention > 0 {
+		s.trashRetention = *trashRetention
+		go s.runTrashPurge()
+	}
+
+	if *aclFile != "" {
+		if err := s.loadACL(*aclFile); err != nil {
+			log.Fatalf("Unable to load acl: %v", err)
+		}
+		go s.runACLReload(*aclFile)
+	}
+
+	if *keyringFile != "" {
+		kr, err := loadKeyring(*keyringFile)
+		if err != nil {
+			log.Fatalf("Unable to load keyring: %v", err)
+		}
+		s.keyring = kr
+		go s.runReencryption()
 	}
 
-	pgc, err := getPGStore()
+	if *tokenFile != "" {
+		tokens, err := loadTokens(*tokenFile)
+		if err != nil {
+			log.Fatalf("Unable to load tokens: %v", err)
+		}
+		s.tokens = tokens
+	}
+	s.requireAuth = *requireAuth
+
+	limits, err := parseRateLimits(*rateLimitFlag)
+	if err != nil {
+		log.Fatalf("Bad rate limits: %v", err)
+	}
+	s.limiter = limits
+
+	bcreds, err := backendCredentials(*backendCA, *backendCert, *backendKey)
+	if err != nil {
+		log.Fatalf("Bad backend TLS config: %v", err)
+	}
+
+	pgc, err := getPGStore(bcreds)
 	if err != nil {
 		log.Fatalf("Cannot dial pgstore client")
 	}
@@ -378,11 +803,16 @@ func main() {
 	s.clients = append(s.clients, &mstore_wrapper{mc: msc})*/
 
 	// Register the rstore client here
-	rsc, err := rstore_client.GetClient()
+	var rsc rstore_client.RStoreClient
+	if *backendCA != "" {
+		rsc, err = getRStore(bcreds)
+	} else {
+		rsc, err = rstore_client.GetClient()
+	}
 	if err != nil {
 		log.Fatalf("Unable to reach rstore client")
 	}
-		s.clients = append(s.clients, pgc)
+	s.clients = append(s.clients, pgc)
 	s.clients = append(s.clients, &rstore_wrapper{rc: rsc})
 
 	client, err := ghbclient.GetClientInternal()
@@ -396,15 +826,27 @@ func main() {
 		log.Fatalf("pstore failed to listen on the serving port %v: %v", *port, err)
 	}
 	size := 1024 * 1024 * 2000
-	gs := grpc.NewServer(
+	opts := []grpc.ServerOption{
 		grpc.MaxSendMsgSize(size),
 		grpc.MaxRecvMsgSize(size),
-	)
+		grpc.ChainUnaryInterceptor(s.unaryAuth, s.unaryLimit),
+		grpc.ChainStreamInterceptor(s.streamAuth, s.streamLimit),
+	}
+	if *tlsCert != "" {
+		creds, err := serverCredentials(*tlsCert, *tlsKey, *tlsClientCA)
+		if err != nil {
+			log.Fatalf("Bad TLS config: %v", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	gs := grpc.NewServer(opts...)
 	pb.RegisterPStoreServiceServer(gs, s)
 	log.Printf("pstore is listening on %v", lis.Addr())
 
 	// Setup prometheus export
 	http.Handle("/metrics", promhttp.Handler())
+	s.registerGateway(http.DefaultServeMux)
+	s.registerUI(http.DefaultServeMux)
 	go func() {
 		http.ListenAndServe(fmt.Sprintf(":%v", *metricsPort), nil)
 	}()
@@ -412,6 +854,25 @@ func main() {
 	// Run the write queue
 	go s.runWriteQueue()
 
+	if *snapshotDir != "" {
+		s.snapshotDir = *snapshotDir
+		go s.runSnapshots(*snapshotDir)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	if err := s.loadDescriptors(ctx, *descriptorFiles); err != nil {
+		log.Printf("Unable to load descriptors: %v", err)
+	}
+	cancel()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+		defer cancel()
+		if err := s.buildIndexes(ctx); err != nil {
+			log.Printf("Unable to build indexes: %v", err)
+		}
+	}()
+
 	if err := gs.Serve(lis); err != nil {
 		log.Fatalf("pstore failed to serve: %v", err)
 	}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	pstore_client "github.com/brotherlogic/pstore/client"
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// testBackend is an in memory pstore which can be switched off, slowed
+// down, or made to behave like Redis and only keep the bytes of a value
+type testBackend struct {
+	name      string
+	client    pstore_client.PStoreClient
+	down      bool
+	dropTypes bool
+	readDelay time.Duration
+}
+
+func getTestBackend(name string) *testBackend {
+	return &testBackend{name: name, client: pstore_client.GetTestClient()}
+}
+
+func (t *testBackend) Name() string {
+	return t.name
+}
+
+func (t *testBackend) Read(ctx context.Context, req *pb.ReadRequest) (*pb.ReadResponse, error) {
+	if t.down {
+		return nil, status.Errorf(codes.Unavailable, "%v is down", t.name)
+	}
+	select {
+	case <-time.After(t.readDelay):
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	return t.client.Read(ctx, req)
+}
+
+func (t *testBackend) Write(ctx context.Context, req *pb.WriteRequest) (*pb.WriteResponse, error) {
+	if t.down {
+		return nil, status.Errorf(codes.Unavailable, "%v is down", t.name)
+	}
+	if t.dropTypes {
+		req = &pb.WriteRequest{Key: req.GetKey(), Value: &anypb.Any{Value: req.GetValue().GetValue()}}
+	}
+	return t.client.Write(ctx, req)
+}
+
+func (t *testBackend) GetKeys(ctx context.Context, req *pb.GetKeysRequest) (*pb.GetKeysResponse, error) {
+	if t.down {
+		return nil, status.Errorf(codes.Unavailable, "%v is down", t.name)
+	}
+	return t.client.GetKeys(ctx, req)
+}
+
+func (t *testBackend) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	if t.down {
+		return nil, status.Errorf(codes.Unavailable, "%v is down", t.name)
+	}
+	return t.client.Delete(ctx, req)
+}
+
+func (t *testBackend) Count(ctx context.Context, req *pb.CountRequest) (*pb.CountResponse, error) {
+	if t.down {
+		return nil, status.Errorf(codes.Unavailable, "%v is down", t.name)
+	}
+	return t.client.Count(ctx, req)
+}
+
+func getTestServer(backends ...*testBackend) *Server {
+	s := &Server{wq: make(chan *WriteElement, 100), registry: newDescriptorRegistry()}
+	for _, b := range backends {
+		s.clients = append(s.clients, b)
+	}
+	return s
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Keys in a namespace are stored as <namespacePrefix><namespace>/<key>
+	namespacePrefix = reservedPrefix + "namespaces/"
+
+	// Callers pick a namespace with this metadata key, no namespace is the shared key space
+	namespaceMetadata = "pstore-namespace"
+)
+
+var (
+	namespaceFile = flag.String("namespaces", "", "Path to the JSON namespace quotas, when set only the listed namespaces are accepted")
+)
+
+var (
+	namespaceKeys = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pstore_namespace_keys",
+	}, []string{"namespace"})
+	namespaceBytes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pstore_namespace_bytes",
+	}, []string{"namespace"})
+	namespaceRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_namespace_rejections",
+	}, []string{"namespace", "limit"})
+)
+
+var namespaceName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// namespaceLimits are the quotas for one namespace, zero means unlimited. Bytes
+// are counted as stored, after compression and encryption.
+//
+//	{
+//	  "team-a": {"max_keys": 10000, "max_bytes": 1073741824, "max_value_size": 1048576},
+//	  "team-b": {}
+//	}
+type namespaceLimits struct {
+	MaxKeys      int64 `json:"max_keys"`
+	MaxBytes     int64 `json:"max_bytes"`
+	MaxValueSize int64 `json:"max_value_size"`
+}
+
+type namespaceUsage struct {
+	keys  int64
+	bytes int64
+
+	// While usage is first worked out: closed once it is ready, and the keys changed meanwhile
+	ready chan struct{}
+	dirty map[string]bool
+	err   error
+}
+
+func loadNamespaces(path string) (map[string]*namespaceLimits, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseNamespaces(data)
+}
+
+func parseNamespaces(data []byte) (map[string]*namespaceLimits, error) {
+	limits := make(map[string]*namespaceLimits)
+	if err := json.Unmarshal(data, &limits); err != nil {
+		return nil, fmt.Errorf("bad namespaces: %w", err)
+	}
+	for name, l := range limits {
+		if !namespaceName.MatchString(name) {
+			return nil, fmt.Errorf("bad namespace name %q", name)
+		}
+		if l == nil {
+			limits[name] = &namespaceLimits{}
+		}
+	}
+	return limits, nil
+}
+
+// namespace returns the namespace the caller asked for, or "" for the shared key space
+func (s *Server) namespace(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(namespaceMetadata)
+	if len(values) == 0 || values[0] == "" {
+		return "", nil
+	}
+	if len(values) > 1 {
+		return "", status.Errorf(codes.InvalidArgument, "only one namespace can be given")
+	}
+	if !namespaceName.MatchString(values[0]) {
+		return "", status.Errorf(codes.InvalidArgument, "bad namespace %q", values[0])
+	}
+	if s.namespaceLimits != nil && s.namespaceLimits[values[0]] == nil {
+		return "", status.Errorf(codes.PermissionDenied, "unknown namespace %q", values[0])
+	}
+	return values[0], nil
+}
+
+// namespacedKey maps a key in a namespace to where it is stored
+func namespacedKey(ns, key string) string {
+	if ns == "" {
+		return key
+	}
+	return namespacePrefix + ns + "/" + key
+}
+
+// keyNamespace returns the namespace a stored key belongs to, or "" for the shared key space
+func keyNamespace(key string) string {
+	rest, ok := strings.CutPrefix(key, namespacePrefix)
+	if !ok {
+		return ""
+	}
+	ns, _, _ := strings.Cut(rest, "/")
+	return ns
+}
+
+// storedSize returns the size of key as held on the primary, or -1 if it is not there
+func (s *Server) storedSize(ctx context.Context, key string) (int64, error) {
+	resp, err := s.runRead(ctx, s.clients[0], &pb.ReadRequest{Key: key})
+	if status.Code(err) == codes.NotFound {
+		return -1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(resp.GetValue().GetValue())), nil
+}
+
+// usage returns the current usage of ns. The first call works it out from the primary,
+// holding up only writes to ns while it does.
+func (s *Server) usage(ctx context.Context, ns string) (*namespaceUsage, error) {
+	s.nsLock.Lock()
+	u, ok := s.nsUsage[ns]
+	if !ok {
+		if s.nsUsage == nil {
+			s.nsUsage = make(map[string]*namespaceUsage)
+		}
+		u = &namespaceUsage{ready: make(chan struct{}), dirty: make(map[string]bool)}
+		s.nsUsage[ns] = u
+		go s.measureUsage(context.WithoutCancel(ctx), ns, u)
+	}
+	ready := u.ready
+	s.nsLock.Unlock()
+
+	select {
+	case <-ready:
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	s.nsLock.Lock()
+	defer s.nsLock.Unlock()
+	if u.err != nil {
+		return nil, u.err
+	}
+	return &namespaceUsage{keys: u.keys, bytes: u.bytes}, nil
+}
+
+// measureUsage totals the keys in ns without holding the namespace lock, then measures
+// again any key changed while it did so until none have been
+func (s *Server) measureUsage(ctx context.Context, ns string, u *namespaceUsage) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute*10)
+	defer cancel()
+
+	sizes := make(map[string]int64)
+	measure := func(keys []string) error {
+		for _, key := range keys {
+			size, err := s.storedSize(ctx, key)
+			if err != nil {
+				return err
+			}
+			sizes[key] = size
+		}
+		return nil
+	}
+
+	keys, err := s.runGetKeys(ctx, s.clients[0], &pb.GetKeysRequest{Prefix: namespacedKey(ns, "")})
+	if err == nil {
+		err = measure(keys.GetKeys())
+	}
+	for err == nil {
+		s.nsLock.Lock()
+		if len(u.dirty) == 0 {
+			for _, size := range sizes {
+				if size >= 0 {
+					u.keys++
+					u.bytes += size
+				}
+			}
+			u.dirty = nil
+			namespaceKeys.With(prometheus.Labels{"namespace": ns}).Set(float64(u.keys))
+			namespaceBytes.With(prometheus.Labels{"namespace": ns}).Set(float64(u.bytes))
+			close(u.ready)
+			s.nsLock.Unlock()
+			return
+		}
+		var dirty []string
+		for key := range u.dirty {
+			dirty = append(dirty, key)
+		}
+		clear(u.dirty)
+		s.nsLock.Unlock()
+		err = measure(dirty)
+	}
+
+	log.Printf("Unable to work out the usage of %v: %v", ns, err)
+	s.nsLock.Lock()
+	u.err = err
+	delete(s.nsUsage, ns)
+	close(u.ready)
+	s.nsLock.Unlock()
+}
+
+// addUsage charges a change to key against ns
+func (s *Server) addUsage(ns, key string, keys, bytes int64) {
+	s.nsLock.Lock()
+	defer s.nsLock.Unlock()
+	u, ok := s.nsUsage[ns]
+	if !ok {
+		return
+	}
+	if u.dirty != nil {
+		u.dirty[key] = true
+		return
+	}
+	u.keys += keys
+	u.bytes += bytes
+	namespaceKeys.With(prometheus.Labels{"namespace": ns}).Set(float64(u.keys))
+	namespaceBytes.With(prometheus.Labels{"namespace": ns}).Set(float64(u.bytes))
+}
+
+// checkQuota decides whether key can be written in ns, returning the change in key count
+// and bytes the write will make. Concurrent writes can overshoot a limit by a little.
+func (s *Server) checkQuota(ctx context.Context, ns, key string, valueSize, storedSize int) (int64, int64, error) {
+	limits := s.namespaceLimits[ns]
+	if limits == nil {
+		limits = &namespaceLimits{}
+	}
+	if limits.MaxValueSize > 0 && int64(valueSize) > limits.MaxValueSize {
+		namespaceRejections.With(prometheus.Labels{"namespace": ns, "limit": "value_size"}).Inc()
+		return 0, 0, status.Errorf(codes.InvalidArgument, "value is %v bytes, %v allows at most %v", valueSize, ns, limits.MaxValueSize)
+	}
+
+	u, err := s.usage(ctx, ns)
+	if err != nil {
+		return 0, 0, err
+	}
+	old, err := s.storedSize(ctx, key)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	dkeys, dbytes := int64(1), int64(storedSize)
+	if old >= 0 {
+		dkeys, dbytes = 0, int64(storedSize)-old
+	}
+	if limits.MaxKeys > 0 && dkeys > 0 && u.keys+dkeys > limits.MaxKeys {
+		namespaceRejections.With(prometheus.Labels{"namespace": ns, "limit": "keys"}).Inc()
+		return 0, 0, status.Errorf(codes.ResourceExhausted, "%v already holds %v keys", ns, u.keys)
+	}
+	if limits.MaxBytes > 0 && dbytes > 0 && u.bytes+dbytes > limits.MaxBytes {
+		namespaceRejections.With(prometheus.Labels{"namespace": ns, "limit": "bytes"}).Inc()
+		return 0, 0, status.Errorf(codes.ResourceExhausted, "%v already holds %v bytes", ns, u.bytes)
+	}
+	return dkeys, dbytes, nil
+}
+
+// stripNamespace maps stored keys back to keys within ns
+func stripNamespace(ns string, keys []string) []string {
+	if ns == "" {
+		return keys
+	}
+	prefix := namespacedKey(ns, "")
+	var result []string
+	for _, key := range keys {
+		if strings.HasPrefix(key, prefix) {
+			result = append(result, strings.TrimPrefix(key, prefix))
+		}
+	}
+	return result
+}
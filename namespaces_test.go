@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func inNamespace(ns string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(namespaceMetadata, ns))
+}
+
+func TestNamespacesAreIsolated(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+
+	s.Write(context.Background(), &pb.WriteRequest{Key: "things/one", Value: &anypb.Any{Value: []byte("shared")}})
+	s.Write(inNamespace("team"), &pb.WriteRequest{Key: "things/one", Value: &anypb.Any{Value: []byte("team")}})
+
+	resp, err := s.Read(inNamespace("team"), &pb.ReadRequest{Key: "things/one"})
+	if err != nil || string(resp.GetValue().GetValue()) != "team" {
+		t.Errorf("Bad namespaced read: %v, %v", resp, err)
+	}
+	resp, err = s.Read(context.Background(), &pb.ReadRequest{Key: "things/one"})
+	if err != nil || string(resp.GetValue().GetValue()) != "shared" {
+		t.Errorf("Bad shared read: %v, %v", resp, err)
+	}
+	if _, err := s.Read(inNamespace("other"), &pb.ReadRequest{Key: "things/one"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Other namespace should not see the key: %v", err)
+	}
+
+	keys, err := s.GetKeys(inNamespace("team"), &pb.GetKeysRequest{Prefix: "things/"})
+	if err != nil || strings.Join(keys.GetKeys(), ",") != "things/one" {
+		t.Errorf("Bad namespaced keys: %v, %v", keys, err)
+	}
+
+	s.Count(inNamespace("team"), &pb.CountRequest{Counter: "hits", Delta: 5})
+	team, _ := s.GetCount(inNamespace("team"), &pb.GetCountRequest{Counter: "hits"})
+	shared, _ := s.GetCount(context.Background(), &pb.GetCountRequest{Counter: "hits"})
+	if team.GetCount() == shared.GetCount() {
+		t.Errorf("Counters should be separate: %v vs %v", team, shared)
+	}
+
+	if _, err := s.Delete(inNamespace("team"), &pb.DeleteRequest{Key: "things/one"}); err != nil {
+		t.Fatalf("Bad delete: %v", err)
+	}
+	if _, err := s.Read(context.Background(), &pb.ReadRequest{Key: "things/one"}); err != nil {
+		t.Errorf("Namespaced delete removed the shared key: %v", err)
+	}
+
+	if _, err := s.Read(inNamespace("bad/name"), &pb.ReadRequest{Key: "things/one"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Bad namespace should fail: %v", err)
+	}
+}
+
+func TestReservedKeysAreHidden(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+	ctx := context.Background()
+	s.Write(inNamespace("team"), &pb.WriteRequest{Key: "things/one", Value: &anypb.Any{Value: []byte("team")}})
+	s.Write(ctx, &pb.WriteRequest{Key: "things/two", Value: &anypb.Any{Value: []byte("shared")}})
+	s.Count(ctx, &pb.CountRequest{Counter: "hits"})
+
+	stored := namespacedKey("team", "things/one")
+	if _, err := s.Read(ctx, &pb.ReadRequest{Key: stored}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Namespaced key was read from outside: %v", err)
+	}
+	if _, err := s.Write(ctx, &pb.WriteRequest{Key: counterKey("hits"), Value: &anypb.Any{}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Counter was overwritten: %v", err)
+	}
+	if _, err := s.Delete(ctx, &pb.DeleteRequest{Key: stored}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Namespaced key was deleted from outside: %v", err)
+	}
+	if _, err := s.GetKeys(ctx, &pb.GetKeysRequest{Prefix: namespacePrefix}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Namespaces were listed from outside: %v", err)
+	}
+
+	keys, err := s.GetKeys(ctx, &pb.GetKeysRequest{AllKeys: true})
+	if err != nil || strings.Join(keys.GetKeys(), ",") != "things/two" {
+		t.Errorf("All keys should only list caller keys: %v, %v", keys, err)
+	}
+}
+
+func TestNamespaceQuotas(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+	limits, err := parseNamespaces([]byte(`{"team": {"max_keys": 2, "max_bytes": 1000, "max_value_size": 100}}`))
+	if err != nil {
+		t.Fatalf("Bad namespaces: %v", err)
+	}
+	s.namespaceLimits = limits
+
+	if _, err := s.Write(inNamespace("stranger"), &pb.WriteRequest{Key: "a", Value: &anypb.Any{Value: []byte("a")}}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Unlisted namespace should be refused: %v", err)
+	}
+	if _, err := s.Write(inNamespace("team"), &pb.WriteRequest{Key: "a", Value: &anypb.Any{Value: make([]byte, 101)}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Large value should be refused: %v", err)
+	}
+
+	for _, key := range []string{"a", "b"} {
+		if _, err := s.Write(inNamespace("team"), &pb.WriteRequest{Key: key, Value: &anypb.Any{Value: []byte(key)}}); err != nil {
+			t.Fatalf("Write %v failed: %v", key, err)
+		}
+	}
+	if _, err := s.Write(inNamespace("team"), &pb.WriteRequest{Key: "c", Value: &anypb.Any{Value: []byte("c")}}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Third key should be refused: %v", err)
+	}
+
+	// Overwrites and writes after a delete fit within the key limit
+	if _, err := s.Write(inNamespace("team"), &pb.WriteRequest{Key: "a", Value: &anypb.Any{Value: []byte("aa")}}); err != nil {
+		t.Errorf("Overwrite should succeed: %v", err)
+	}
+	s.Delete(inNamespace("team"), &pb.DeleteRequest{Key: "b"})
+	if _, err := s.Write(inNamespace("team"), &pb.WriteRequest{Key: "c", Value: &anypb.Any{Value: []byte("c")}}); err != nil {
+		t.Errorf("Write after delete should succeed: %v", err)
+	}
+
+	u, err := s.usage(context.Background(), "team")
+	if err != nil || u.keys != 2 {
+		t.Errorf("Bad usage: %+v, %v", u, err)
+	}
+}
+
+func TestUsageDoesNotBlockOtherNamespaces(t *testing.T) {
+	primary := getTestBackend("primary")
+	s := getTestServer(primary)
+	for i := 0; i < 10; i++ {
+		primary.Write(context.Background(), &pb.WriteRequest{Key: namespacedKey("big", fmt.Sprintf("k%v", i)), Value: &anypb.Any{Value: []byte("v")}})
+	}
+	primary.readDelay = time.Millisecond * 50
+
+	measured := make(chan error)
+	go func() {
+		_, err := s.usage(context.Background(), "big")
+		measured <- err
+	}()
+	time.Sleep(time.Millisecond * 10)
+
+	start := time.Now()
+	if _, err := s.Write(inNamespace("team"), &pb.WriteRequest{Key: "a", Value: &anypb.Any{Value: []byte("a")}}); err != nil {
+		t.Fatalf("Bad write: %v", err)
+	}
+	if took := time.Since(start); took > time.Millisecond*300 {
+		t.Errorf("Write to team waited on the usage of big: %v", took)
+	}
+	if err := <-measured; err != nil {
+		t.Errorf("Bad usage: %v", err)
+	}
+}
+
+func TestRestoreChargesKeyNamespace(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+	s.changelog = true
+	s.snapshotDir = t.TempDir()
+	ctx := context.Background()
+
+	s.Write(inNamespace("team"), &pb.WriteRequest{Key: "a", Value: &anypb.Any{Value: []byte("a")}})
+	if _, err := s.takeSnapshot(ctx, s.snapshotDir, 2); err != nil {
+		t.Fatalf("Bad snapshot: %v", err)
+	}
+	good := time.Now()
+	s.Write(inNamespace("team"), &pb.WriteRequest{Key: "b", Value: &anypb.Any{Value: []byte("b")}})
+
+	if _, err := s.Restore(ctx, &pb.RestoreRequest{Timestamp: good.UnixNano()}); err != nil {
+		t.Fatalf("Bad restore: %v", err)
+	}
+	u, err := s.usage(ctx, "team")
+	if err != nil || u.keys != 1 || u.bytes != int64(len(valueBytes(t, s, namespacedKey("team", "a")))) {
+		t.Errorf("Restore did not charge the namespace it changed: %+v, %v", u, err)
+	}
+}
+
+func valueBytes(t *testing.T, s *Server, key string) []byte {
+	resp, err := s.clients[0].Read(context.Background(), &pb.ReadRequest{Key: key})
+	if err != nil {
+		t.Fatalf("Unable to read %v: %v", key, err)
+	}
+	return resp.GetValue().GetValue()
+}
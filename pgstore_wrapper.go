@@ -6,12 +6,12 @@ import (
 
 	pb "github.com/brotherlogic/pstore/proto"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/credentials"
 )
 
-func getPGStore() (*pgstore_wrapper, error) {
+func getPGStore(creds credentials.TransportCredentials) (*pgstore_wrapper, error) {
 	conn, err := grpc.Dial("pgstore.pgstore:8080",
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(creds),
 		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(4000*1024*1024)))
 	if err != nil {
 		return nil, fmt.Errorf("dial error on %v -> %w", "pgstore.pgstore:8080", err)
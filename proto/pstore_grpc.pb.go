@@ -1,7 +1,7 @@
 // Code generated by protoc-gen-go-grpc. DO NOT EDIT.
 // versions:
-// - protoc-gen-go-grpc v1.2.0
-// - protoc             v3.12.4
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
 // source: pstore.proto
 
 package proto
@@ -15,8 +15,30 @@ import (
 
 // This is a compile-time assertion to ensure that this generated file
 // is compatible with the grpc package it is being compiled against.
-// Requires gRPC-Go v1.32.0 or later.
-const _ = grpc.SupportPackageIsVersion7
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PStoreService_Read_FullMethodName                = "/pstore.PStoreService/Read"
+	PStoreService_Write_FullMethodName               = "/pstore.PStoreService/Write"
+	PStoreService_GetKeys_FullMethodName             = "/pstore.PStoreService/GetKeys"
+	PStoreService_Delete_FullMethodName              = "/pstore.PStoreService/Delete"
+	PStoreService_Count_FullMethodName               = "/pstore.PStoreService/Count"
+	PStoreService_GetCount_FullMethodName            = "/pstore.PStoreService/GetCount"
+	PStoreService_ResetCount_FullMethodName          = "/pstore.PStoreService/ResetCount"
+	PStoreService_AllocateIDs_FullMethodName         = "/pstore.PStoreService/AllocateIDs"
+	PStoreService_RegisterDescriptors_FullMethodName = "/pstore.PStoreService/RegisterDescriptors"
+	PStoreService_QueryIndex_FullMethodName          = "/pstore.PStoreService/QueryIndex"
+	PStoreService_Scan_FullMethodName                = "/pstore.PStoreService/Scan"
+	PStoreService_Aggregate_FullMethodName           = "/pstore.PStoreService/Aggregate"
+	PStoreService_QueryAudit_FullMethodName          = "/pstore.PStoreService/QueryAudit"
+	PStoreService_Changes_FullMethodName             = "/pstore.PStoreService/Changes"
+	PStoreService_Export_FullMethodName              = "/pstore.PStoreService/Export"
+	PStoreService_Import_FullMethodName              = "/pstore.PStoreService/Import"
+	PStoreService_Restore_FullMethodName             = "/pstore.PStoreService/Restore"
+	PStoreService_ListTrash_FullMethodName           = "/pstore.PStoreService/ListTrash"
+	PStoreService_Undelete_FullMethodName            = "/pstore.PStoreService/Undelete"
+)
 
 // PStoreServiceClient is the client API for PStoreService service.
 //
@@ -27,6 +49,20 @@ type PStoreServiceClient interface {
 	GetKeys(ctx context.Context, in *GetKeysRequest, opts ...grpc.CallOption) (*GetKeysResponse, error)
 	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
 	Count(ctx context.Context, in *CountRequest, opts ...grpc.CallOption) (*CountResponse, error)
+	GetCount(ctx context.Context, in *GetCountRequest, opts ...grpc.CallOption) (*GetCountResponse, error)
+	ResetCount(ctx context.Context, in *ResetCountRequest, opts ...grpc.CallOption) (*ResetCountResponse, error)
+	AllocateIDs(ctx context.Context, in *AllocateIDsRequest, opts ...grpc.CallOption) (*AllocateIDsResponse, error)
+	RegisterDescriptors(ctx context.Context, in *RegisterDescriptorsRequest, opts ...grpc.CallOption) (*RegisterDescriptorsResponse, error)
+	QueryIndex(ctx context.Context, in *QueryIndexRequest, opts ...grpc.CallOption) (*QueryIndexResponse, error)
+	Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ScanResponse], error)
+	Aggregate(ctx context.Context, in *AggregateRequest, opts ...grpc.CallOption) (*AggregateResponse, error)
+	QueryAudit(ctx context.Context, in *QueryAuditRequest, opts ...grpc.CallOption) (*QueryAuditResponse, error)
+	Changes(ctx context.Context, in *ChangesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChangeEvent], error)
+	Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExportChunk], error)
+	Import(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ImportRequest, ImportResponse], error)
+	Restore(ctx context.Context, in *RestoreRequest, opts ...grpc.CallOption) (*RestoreResponse, error)
+	ListTrash(ctx context.Context, in *ListTrashRequest, opts ...grpc.CallOption) (*ListTrashResponse, error)
+	Undelete(ctx context.Context, in *UndeleteRequest, opts ...grpc.CallOption) (*UndeleteResponse, error)
 }
 
 type pStoreServiceClient struct {
@@ -38,8 +74,9 @@ func NewPStoreServiceClient(cc grpc.ClientConnInterface) PStoreServiceClient {
 }
 
 func (c *pStoreServiceClient) Read(ctx context.Context, in *ReadRequest, opts ...grpc.CallOption) (*ReadResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ReadResponse)
-	err := c.cc.Invoke(ctx, "/pstore.PStoreService/Read", in, out, opts...)
+	err := c.cc.Invoke(ctx, PStoreService_Read_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -47,8 +84,9 @@ func (c *pStoreServiceClient) Read(ctx context.Context, in *ReadRequest, opts ..
 }
 
 func (c *pStoreServiceClient) Write(ctx context.Context, in *WriteRequest, opts ...grpc.CallOption) (*WriteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(WriteResponse)
-	err := c.cc.Invoke(ctx, "/pstore.PStoreService/Write", in, out, opts...)
+	err := c.cc.Invoke(ctx, PStoreService_Write_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -56,8 +94,9 @@ func (c *pStoreServiceClient) Write(ctx context.Context, in *WriteRequest, opts
 }
 
 func (c *pStoreServiceClient) GetKeys(ctx context.Context, in *GetKeysRequest, opts ...grpc.CallOption) (*GetKeysResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(GetKeysResponse)
-	err := c.cc.Invoke(ctx, "/pstore.PStoreService/GetKeys", in, out, opts...)
+	err := c.cc.Invoke(ctx, PStoreService_GetKeys_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -65,8 +104,9 @@ func (c *pStoreServiceClient) GetKeys(ctx context.Context, in *GetKeysRequest, o
 }
 
 func (c *pStoreServiceClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(DeleteResponse)
-	err := c.cc.Invoke(ctx, "/pstore.PStoreService/Delete", in, out, opts...)
+	err := c.cc.Invoke(ctx, PStoreService_Delete_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -74,8 +114,179 @@ func (c *pStoreServiceClient) Delete(ctx context.Context, in *DeleteRequest, opt
 }
 
 func (c *pStoreServiceClient) Count(ctx context.Context, in *CountRequest, opts ...grpc.CallOption) (*CountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(CountResponse)
-	err := c.cc.Invoke(ctx, "/pstore.PStoreService/Count", in, out, opts...)
+	err := c.cc.Invoke(ctx, PStoreService_Count_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pStoreServiceClient) GetCount(ctx context.Context, in *GetCountRequest, opts ...grpc.CallOption) (*GetCountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetCountResponse)
+	err := c.cc.Invoke(ctx, PStoreService_GetCount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pStoreServiceClient) ResetCount(ctx context.Context, in *ResetCountRequest, opts ...grpc.CallOption) (*ResetCountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResetCountResponse)
+	err := c.cc.Invoke(ctx, PStoreService_ResetCount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pStoreServiceClient) AllocateIDs(ctx context.Context, in *AllocateIDsRequest, opts ...grpc.CallOption) (*AllocateIDsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AllocateIDsResponse)
+	err := c.cc.Invoke(ctx, PStoreService_AllocateIDs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pStoreServiceClient) RegisterDescriptors(ctx context.Context, in *RegisterDescriptorsRequest, opts ...grpc.CallOption) (*RegisterDescriptorsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RegisterDescriptorsResponse)
+	err := c.cc.Invoke(ctx, PStoreService_RegisterDescriptors_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pStoreServiceClient) QueryIndex(ctx context.Context, in *QueryIndexRequest, opts ...grpc.CallOption) (*QueryIndexResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueryIndexResponse)
+	err := c.cc.Invoke(ctx, PStoreService_QueryIndex_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pStoreServiceClient) Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ScanResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PStoreService_ServiceDesc.Streams[0], PStoreService_Scan_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ScanRequest, ScanResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PStoreService_ScanClient = grpc.ServerStreamingClient[ScanResponse]
+
+func (c *pStoreServiceClient) Aggregate(ctx context.Context, in *AggregateRequest, opts ...grpc.CallOption) (*AggregateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AggregateResponse)
+	err := c.cc.Invoke(ctx, PStoreService_Aggregate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pStoreServiceClient) QueryAudit(ctx context.Context, in *QueryAuditRequest, opts ...grpc.CallOption) (*QueryAuditResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueryAuditResponse)
+	err := c.cc.Invoke(ctx, PStoreService_QueryAudit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pStoreServiceClient) Changes(ctx context.Context, in *ChangesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChangeEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PStoreService_ServiceDesc.Streams[1], PStoreService_Changes_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ChangesRequest, ChangeEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PStoreService_ChangesClient = grpc.ServerStreamingClient[ChangeEvent]
+
+func (c *pStoreServiceClient) Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExportChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PStoreService_ServiceDesc.Streams[2], PStoreService_Export_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExportRequest, ExportChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PStoreService_ExportClient = grpc.ServerStreamingClient[ExportChunk]
+
+func (c *pStoreServiceClient) Import(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ImportRequest, ImportResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PStoreService_ServiceDesc.Streams[3], PStoreService_Import_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ImportRequest, ImportResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PStoreService_ImportClient = grpc.ClientStreamingClient[ImportRequest, ImportResponse]
+
+func (c *pStoreServiceClient) Restore(ctx context.Context, in *RestoreRequest, opts ...grpc.CallOption) (*RestoreResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RestoreResponse)
+	err := c.cc.Invoke(ctx, PStoreService_Restore_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pStoreServiceClient) ListTrash(ctx context.Context, in *ListTrashRequest, opts ...grpc.CallOption) (*ListTrashResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListTrashResponse)
+	err := c.cc.Invoke(ctx, PStoreService_ListTrash_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pStoreServiceClient) Undelete(ctx context.Context, in *UndeleteRequest, opts ...grpc.CallOption) (*UndeleteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UndeleteResponse)
+	err := c.cc.Invoke(ctx, PStoreService_Undelete_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -84,18 +295,35 @@ func (c *pStoreServiceClient) Count(ctx context.Context, in *CountRequest, opts
 
 // PStoreServiceServer is the server API for PStoreService service.
 // All implementations should embed UnimplementedPStoreServiceServer
-// for forward compatibility
+// for forward compatibility.
 type PStoreServiceServer interface {
 	Read(context.Context, *ReadRequest) (*ReadResponse, error)
 	Write(context.Context, *WriteRequest) (*WriteResponse, error)
 	GetKeys(context.Context, *GetKeysRequest) (*GetKeysResponse, error)
 	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
 	Count(context.Context, *CountRequest) (*CountResponse, error)
+	GetCount(context.Context, *GetCountRequest) (*GetCountResponse, error)
+	ResetCount(context.Context, *ResetCountRequest) (*ResetCountResponse, error)
+	AllocateIDs(context.Context, *AllocateIDsRequest) (*AllocateIDsResponse, error)
+	RegisterDescriptors(context.Context, *RegisterDescriptorsRequest) (*RegisterDescriptorsResponse, error)
+	QueryIndex(context.Context, *QueryIndexRequest) (*QueryIndexResponse, error)
+	Scan(*ScanRequest, grpc.ServerStreamingServer[ScanResponse]) error
+	Aggregate(context.Context, *AggregateRequest) (*AggregateResponse, error)
+	QueryAudit(context.Context, *QueryAuditRequest) (*QueryAuditResponse, error)
+	Changes(*ChangesRequest, grpc.ServerStreamingServer[ChangeEvent]) error
+	Export(*ExportRequest, grpc.ServerStreamingServer[ExportChunk]) error
+	Import(grpc.ClientStreamingServer[ImportRequest, ImportResponse]) error
+	Restore(context.Context, *RestoreRequest) (*RestoreResponse, error)
+	ListTrash(context.Context, *ListTrashRequest) (*ListTrashResponse, error)
+	Undelete(context.Context, *UndeleteRequest) (*UndeleteResponse, error)
 }
 
-// UnimplementedPStoreServiceServer should be embedded to have forward compatible implementations.
-type UnimplementedPStoreServiceServer struct {
-}
+// UnimplementedPStoreServiceServer should be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPStoreServiceServer struct{}
 
 func (UnimplementedPStoreServiceServer) Read(context.Context, *ReadRequest) (*ReadResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Read not implemented")
@@ -112,6 +340,49 @@ func (UnimplementedPStoreServiceServer) Delete(context.Context, *DeleteRequest)
 func (UnimplementedPStoreServiceServer) Count(context.Context, *CountRequest) (*CountResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Count not implemented")
 }
+func (UnimplementedPStoreServiceServer) GetCount(context.Context, *GetCountRequest) (*GetCountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCount not implemented")
+}
+func (UnimplementedPStoreServiceServer) ResetCount(context.Context, *ResetCountRequest) (*ResetCountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetCount not implemented")
+}
+func (UnimplementedPStoreServiceServer) AllocateIDs(context.Context, *AllocateIDsRequest) (*AllocateIDsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AllocateIDs not implemented")
+}
+func (UnimplementedPStoreServiceServer) RegisterDescriptors(context.Context, *RegisterDescriptorsRequest) (*RegisterDescriptorsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterDescriptors not implemented")
+}
+func (UnimplementedPStoreServiceServer) QueryIndex(context.Context, *QueryIndexRequest) (*QueryIndexResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryIndex not implemented")
+}
+func (UnimplementedPStoreServiceServer) Scan(*ScanRequest, grpc.ServerStreamingServer[ScanResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Scan not implemented")
+}
+func (UnimplementedPStoreServiceServer) Aggregate(context.Context, *AggregateRequest) (*AggregateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Aggregate not implemented")
+}
+func (UnimplementedPStoreServiceServer) QueryAudit(context.Context, *QueryAuditRequest) (*QueryAuditResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryAudit not implemented")
+}
+func (UnimplementedPStoreServiceServer) Changes(*ChangesRequest, grpc.ServerStreamingServer[ChangeEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Changes not implemented")
+}
+func (UnimplementedPStoreServiceServer) Export(*ExportRequest, grpc.ServerStreamingServer[ExportChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method Export not implemented")
+}
+func (UnimplementedPStoreServiceServer) Import(grpc.ClientStreamingServer[ImportRequest, ImportResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Import not implemented")
+}
+func (UnimplementedPStoreServiceServer) Restore(context.Context, *RestoreRequest) (*RestoreResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Restore not implemented")
+}
+func (UnimplementedPStoreServiceServer) ListTrash(context.Context, *ListTrashRequest) (*ListTrashResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTrash not implemented")
+}
+func (UnimplementedPStoreServiceServer) Undelete(context.Context, *UndeleteRequest) (*UndeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Undelete not implemented")
+}
+func (UnimplementedPStoreServiceServer) testEmbeddedByValue() {}
 
 // UnsafePStoreServiceServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to PStoreServiceServer will
@@ -121,6 +392,13 @@ type UnsafePStoreServiceServer interface {
 }
 
 func RegisterPStoreServiceServer(s grpc.ServiceRegistrar, srv PStoreServiceServer) {
+	// If the following call pancis, it indicates UnimplementedPStoreServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
 	s.RegisterService(&PStoreService_ServiceDesc, srv)
 }
 
@@ -134,7 +412,7 @@ func _PStoreService_Read_Handler(srv interface{}, ctx context.Context, dec func(
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/pstore.PStoreService/Read",
+		FullMethod: PStoreService_Read_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return srv.(PStoreServiceServer).Read(ctx, req.(*ReadRequest))
@@ -152,7 +430,7 @@ func _PStoreService_Write_Handler(srv interface{}, ctx context.Context, dec func
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/pstore.PStoreService/Write",
+		FullMethod: PStoreService_Write_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return srv.(PStoreServiceServer).Write(ctx, req.(*WriteRequest))
@@ -170,7 +448,7 @@ func _PStoreService_GetKeys_Handler(srv interface{}, ctx context.Context, dec fu
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/pstore.PStoreService/GetKeys",
+		FullMethod: PStoreService_GetKeys_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return srv.(PStoreServiceServer).GetKeys(ctx, req.(*GetKeysRequest))
@@ -188,7 +466,7 @@ func _PStoreService_Delete_Handler(srv interface{}, ctx context.Context, dec fun
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/pstore.PStoreService/Delete",
+		FullMethod: PStoreService_Delete_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return srv.(PStoreServiceServer).Delete(ctx, req.(*DeleteRequest))
@@ -206,7 +484,7 @@ func _PStoreService_Count_Handler(srv interface{}, ctx context.Context, dec func
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/pstore.PStoreService/Count",
+		FullMethod: PStoreService_Count_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return srv.(PStoreServiceServer).Count(ctx, req.(*CountRequest))
@@ -214,6 +492,226 @@ func _PStoreService_Count_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PStoreService_GetCount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PStoreServiceServer).GetCount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PStoreService_GetCount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PStoreServiceServer).GetCount(ctx, req.(*GetCountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PStoreService_ResetCount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetCountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PStoreServiceServer).ResetCount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PStoreService_ResetCount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PStoreServiceServer).ResetCount(ctx, req.(*ResetCountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PStoreService_AllocateIDs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AllocateIDsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PStoreServiceServer).AllocateIDs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PStoreService_AllocateIDs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PStoreServiceServer).AllocateIDs(ctx, req.(*AllocateIDsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PStoreService_RegisterDescriptors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterDescriptorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PStoreServiceServer).RegisterDescriptors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PStoreService_RegisterDescriptors_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PStoreServiceServer).RegisterDescriptors(ctx, req.(*RegisterDescriptorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PStoreService_QueryIndex_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryIndexRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PStoreServiceServer).QueryIndex(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PStoreService_QueryIndex_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PStoreServiceServer).QueryIndex(ctx, req.(*QueryIndexRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PStoreService_Scan_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ScanRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PStoreServiceServer).Scan(m, &grpc.GenericServerStream[ScanRequest, ScanResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PStoreService_ScanServer = grpc.ServerStreamingServer[ScanResponse]
+
+func _PStoreService_Aggregate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AggregateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PStoreServiceServer).Aggregate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PStoreService_Aggregate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PStoreServiceServer).Aggregate(ctx, req.(*AggregateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PStoreService_QueryAudit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryAuditRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PStoreServiceServer).QueryAudit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PStoreService_QueryAudit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PStoreServiceServer).QueryAudit(ctx, req.(*QueryAuditRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PStoreService_Changes_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ChangesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PStoreServiceServer).Changes(m, &grpc.GenericServerStream[ChangesRequest, ChangeEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PStoreService_ChangesServer = grpc.ServerStreamingServer[ChangeEvent]
+
+func _PStoreService_Export_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PStoreServiceServer).Export(m, &grpc.GenericServerStream[ExportRequest, ExportChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PStoreService_ExportServer = grpc.ServerStreamingServer[ExportChunk]
+
+func _PStoreService_Import_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PStoreServiceServer).Import(&grpc.GenericServerStream[ImportRequest, ImportResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PStoreService_ImportServer = grpc.ClientStreamingServer[ImportRequest, ImportResponse]
+
+func _PStoreService_Restore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PStoreServiceServer).Restore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PStoreService_Restore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PStoreServiceServer).Restore(ctx, req.(*RestoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PStoreService_ListTrash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTrashRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PStoreServiceServer).ListTrash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PStoreService_ListTrash_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PStoreServiceServer).ListTrash(ctx, req.(*ListTrashRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PStoreService_Undelete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UndeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PStoreServiceServer).Undelete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PStoreService_Undelete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PStoreServiceServer).Undelete(ctx, req.(*UndeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // PStoreService_ServiceDesc is the grpc.ServiceDesc for PStoreService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -241,7 +739,68 @@ var PStoreService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Count",
 			Handler:    _PStoreService_Count_Handler,
 		},
+		{
+			MethodName: "GetCount",
+			Handler:    _PStoreService_GetCount_Handler,
+		},
+		{
+			MethodName: "ResetCount",
+			Handler:    _PStoreService_ResetCount_Handler,
+		},
+		{
+			MethodName: "AllocateIDs",
+			Handler:    _PStoreService_AllocateIDs_Handler,
+		},
+		{
+			MethodName: "RegisterDescriptors",
+			Handler:    _PStoreService_RegisterDescriptors_Handler,
+		},
+		{
+			MethodName: "QueryIndex",
+			Handler:    _PStoreService_QueryIndex_Handler,
+		},
+		{
+			MethodName: "Aggregate",
+			Handler:    _PStoreService_Aggregate_Handler,
+		},
+		{
+			MethodName: "QueryAudit",
+			Handler:    _PStoreService_QueryAudit_Handler,
+		},
+		{
+			MethodName: "Restore",
+			Handler:    _PStoreService_Restore_Handler,
+		},
+		{
+			MethodName: "ListTrash",
+			Handler:    _PStoreService_ListTrash_Handler,
+		},
+		{
+			MethodName: "Undelete",
+			Handler:    _PStoreService_Undelete_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Scan",
+			Handler:       _PStoreService_Scan_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Changes",
+			Handler:       _PStoreService_Changes_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Export",
+			Handler:       _PStoreService_Export_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Import",
+			Handler:       _PStoreService_Import_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "pstore.proto",
 }
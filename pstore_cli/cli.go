@@ -1,6 +1,7 @@
 package main
 
 import (
+	"io"
 	"log"
 	"os"
 	"time"
@@ -16,7 +17,7 @@ func main() {
 	ctx, cancel := utils.ManualContext("pstore-cli", time.Hour)
 	defer cancel()
 
-	size := 1024*1024*2000
+	size := 1024 * 1024 * 2000
 	conn, err := grpc.Dial(os.Args[1], grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(size)))
 	if err != nil {
 		log.Fatalf("Bad dial: %v", err)
@@ -24,12 +25,125 @@ func main() {
 
 	client := pbps.NewPStoreServiceClient(conn)
 
-	result, err := client.GetKeys(ctx, &pbps.GetKeysRequest{AllKeys: true})
-	if err != nil {
-		log.Printf("Error: %v", err)
+	command := "keys"
+	if len(os.Args) > 2 {
+		command = os.Args[2]
+	}
+
+	switch command {
+	case "keys":
+		result, err := client.GetKeys(ctx, &pbps.GetKeysRequest{AllKeys: true})
+		if err != nil {
+			log.Printf("Error: %v", err)
+		}
+		log.Printf("Found %v keys", len(result.GetKeys()))
+		/*for _, key := range result.GetKeys() {
+			log.Printf("Key: %v", key)
+		}*/
+	case "read":
+		result, err := client.Read(ctx, &pbps.ReadRequest{Key: os.Args[3], RenderJson: true})
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if result.GetJson() != "" {
+			log.Printf("%v (%v): %v", os.Args[3], result.GetValue().GetTypeUrl(), result.GetJson())
+		} else {
+			log.Printf("%v (%v): %v bytes, type is not registered", os.Args[3], result.GetValue().GetTypeUrl(), len(result.GetValue().GetValue()))
+		}
+	case "register":
+		data, err := os.ReadFile(os.Args[3])
+		if err != nil {
+			log.Fatalf("Unable to read descriptors: %v", err)
+		}
+		result, err := client.RegisterDescriptors(ctx, &pbps.RegisterDescriptorsRequest{DescriptorSet: data})
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		log.Printf("Registered, server now knows %v messages", len(result.GetMessages()))
+	case "export":
+		// export <file> [prefix]
+		file, err := os.Create(os.Args[3])
+		if err != nil {
+			log.Fatalf("Unable to create archive: %v", err)
+		}
+		defer file.Close()
+		req := &pbps.ExportRequest{}
+		if len(os.Args) > 4 {
+			req.Prefix = os.Args[4]
+		}
+		stream, err := client.Export(ctx, req)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		for {
+			chunk, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			if _, err := file.Write(chunk.GetData()); err != nil {
+				log.Fatalf("Unable to write archive: %v", err)
+			}
+		}
+		log.Printf("Exported to %v", os.Args[3])
+	case "import":
+		// import <file> [skip] [dry_run]
+		data, err := os.ReadFile(os.Args[3])
+		if err != nil {
+			log.Fatalf("Unable to read archive: %v", err)
+		}
+		options := &pbps.ImportRequest{}
+		for _, arg := range os.Args[4:] {
+			switch arg {
+			case "skip":
+				options.Existing = pbps.ImportRequest_SKIP
+			case "dry_run":
+				options.DryRun = true
+			default:
+				log.Fatalf("Unknown import option %v, use skip or dry_run", arg)
+			}
+		}
+		stream, err := client.Import(ctx)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if err := stream.Send(options); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		for len(data) > 0 {
+			n := min(len(data), 1024*1024)
+			if err := stream.Send(&pbps.ImportRequest{Data: data[:n]}); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			data = data[n:]
+		}
+		result, err := stream.CloseAndRecv()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		log.Printf("Imported %v keys, skipped %v", result.GetWritten(), result.GetSkipped())
+	case "restore":
+		// restore <RFC3339 time> [prefix] [dry_run]
+		when, err := time.Parse(time.RFC3339, os.Args[3])
+		if err != nil {
+			log.Fatalf("Bad time: %v", err)
+		}
+		req := &pbps.RestoreRequest{Timestamp: when.UnixNano()}
+		for _, arg := range os.Args[4:] {
+			if arg == "dry_run" {
+				req.DryRun = true
+			} else {
+				req.Prefix = arg
+			}
+		}
+		result, err := client.Restore(ctx, req)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		log.Printf("Restored from %v with %v changes replayed: wrote %v keys, deleted %v", result.GetSnapshot(), result.GetReplayed(), result.GetWritten(), result.GetDeleted())
+	default:
+		log.Fatalf("Unknown command %v, use keys, read, register, export, import or restore", command)
 	}
-	log.Printf("Found %v keys", len(result.GetKeys()))
-	/*for _, key := range result.GetKeys() {
-		log.Printf("Key: %v", key)
-	}*/
 }
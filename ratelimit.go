@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	rateLimitFlag  = flag.String("rate_limits", "", "Token buckets for each caller as [caller/]Method=rate:burst, e.g. GetKeys=1:5,batch/Read=100:200")
+	shedQueueDepth = flag.Int("shed_queue_depth", 80, "Shed low priority requests once the write queue holds this many elements, 0 disables")
+	shedLatency    = flag.Duration("shed_latency", time.Second*2, "Shed low priority requests once average backend latency passes this, 0 disables")
+)
+
+var (
+	rateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_rate_limited",
+	}, []string{"caller", "method"})
+	shedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_shed",
+	}, []string{"method", "reason"})
+	backendLatency = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pstore_backend_latency_average",
+	})
+)
+
+// Callers mark work that can wait with pstore-priority: low
+const priorityMetadata = "pstore-priority"
+
+type rateLimit struct {
+	rate  float64
+	burst float64
+}
+
+type bucket struct {
+	limit  *rateLimit
+	tokens float64
+	last   time.Time
+}
+
+// full reports whether the bucket has refilled by now, when it is no different to a new one
+func (b *bucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*b.limit.rate >= b.limit.burst
+}
+
+// Buckets are swept for ones that have refilled this often, so callers that come
+// and go do not hold on to one each
+const bucketSweep = time.Minute
+
+// limiter holds a token bucket for each caller and method. A limit set for a
+// caller replaces the limit for that method, and methods with no limit are free.
+type limiter struct {
+	lock    sync.Mutex
+	limits  map[string]*rateLimit
+	buckets map[string]*bucket
+	swept   time.Time
+	now     func() time.Time
+}
+
+func parseRateLimits(flagValue string) (*limiter, error) {
+	l := &limiter{limits: make(map[string]*rateLimit), buckets: make(map[string]*bucket), now: time.Now}
+	for _, entry := range strings.Split(flagValue, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		nameLimit := strings.SplitN(entry, "=", 2)
+		if len(nameLimit) != 2 || nameLimit[0] == "" {
+			return nil, fmt.Errorf("bad rate limit %q", entry)
+		}
+		rateBurst := strings.SplitN(nameLimit[1], ":", 2)
+		if len(rateBurst) != 2 {
+			return nil, fmt.Errorf("bad rate limit %q", entry)
+		}
+		rate, err := strconv.ParseFloat(rateBurst[0], 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("bad rate in %q", entry)
+		}
+		burst, err := strconv.ParseFloat(rateBurst[1], 64)
+		if err != nil || burst < 1 {
+			return nil, fmt.Errorf("bad burst in %q", entry)
+		}
+		l.limits[nameLimit[0]] = &rateLimit{rate: rate, burst: burst}
+	}
+	return l, nil
+}
+
+// allow takes a token from the bucket for caller and method
+func (l *limiter) allow(caller, method string) bool {
+	if l == nil {
+		return true
+	}
+	key := caller + "/" + method
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	limit, ok := l.limits[key]
+	if !ok {
+		limit, ok = l.limits[method]
+	}
+	if !ok {
+		return true
+	}
+
+	now := l.now()
+	if now.Sub(l.swept) >= bucketSweep {
+		for k, b := range l.buckets {
+			if b.full(now) {
+				delete(l.buckets, k)
+			}
+		}
+		l.swept = now
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limit: limit, tokens: limit.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * limit.rate
+	if b.tokens > limit.burst {
+		b.tokens = limit.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// observeLatency folds a backend call into the running average used for shedding
+func (s *Server) observeLatency(d time.Duration) {
+	s.latencyLock.Lock()
+	defer s.latencyLock.Unlock()
+	s.latency = s.latency*0.9 + d.Seconds()*0.1
+	backendLatency.Set(s.latency)
+}
+
+func (s *Server) averageLatency() time.Duration {
+	s.latencyLock.Lock()
+	defer s.latencyLock.Unlock()
+	return time.Duration(s.latency * float64(time.Second))
+}
+
+// Streams that walk a whole prefix or store, shed like full key listings
+var bulkMethods = map[string]bool{"Scan": true, "Export": true}
+
+// lowPriority picks out work that is shed first: anything the caller marks as
+// low priority, full key listings and bulk streams
+func lowPriority(ctx context.Context, method string, req interface{}) bool {
+	if bulkMethods[method] {
+		return true
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, p := range md.Get(priorityMetadata) {
+		if p == "low" {
+			return true
+		}
+	}
+	if gk, ok := req.(*pb.GetKeysRequest); ok && gk.GetAllKeys() {
+		return true
+	}
+	return false
+}
+
+// admit applies the rate limits and, for low priority work, load shedding
+func (s *Server) admit(ctx context.Context, fullMethod string, req interface{}) error {
+	method := path.Base(fullMethod)
+	if err := s.rateLimit(ctx, method); err != nil {
+		return err
+	}
+	return s.shed(method, lowPriority(ctx, method, req))
+}
+
+func (s *Server) rateLimit(ctx context.Context, method string) error {
+	caller := callerFrom(ctx)
+	if !s.limiter.allow(caller, method) {
+		rateLimited.With(prometheus.Labels{"caller": caller, "method": method}).Inc()
+		return status.Errorf(codes.ResourceExhausted, "%v is over its %v rate limit", caller, method)
+	}
+	return nil
+}
+
+func (s *Server) shed(method string, low bool) error {
+	if !low {
+		return nil
+	}
+	if *shedQueueDepth > 0 && len(s.wq) >= *shedQueueDepth {
+		shedCount.With(prometheus.Labels{"method": method, "reason": "queue"}).Inc()
+		return status.Errorf(codes.ResourceExhausted, "shedding low priority %v, write queue holds %v", method, len(s.wq))
+	}
+	if *shedLatency > 0 && s.averageLatency() > *shedLatency {
+		shedCount.With(prometheus.Labels{"method": method, "reason": "latency"}).Inc()
+		return status.Errorf(codes.ResourceExhausted, "shedding low priority %v, backends are slow (%v)", method, s.averageLatency())
+	}
+	return nil
+}
+
+func (s *Server) unaryLimit(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := s.admit(ctx, info.FullMethod, req); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamLimit admits a stream before its first message, so streams are classified by method alone
+func (s *Server) streamLimit(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.admit(ss.Context(), info.FullMethod, nil); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimits(t *testing.T) {
+	l, err := parseRateLimits("GetKeys=1:2,batch/GetKeys=0.5:1")
+	if err != nil {
+		t.Fatalf("Bad limits: %v", err)
+	}
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	if !l.allow("web", "GetKeys") || !l.allow("web", "GetKeys") || l.allow("web", "GetKeys") {
+		t.Errorf("Burst of two should be allowed and no more")
+	}
+	if !l.allow("other", "GetKeys") {
+		t.Errorf("Callers should have their own buckets")
+	}
+	if !l.allow("batch", "GetKeys") || l.allow("batch", "GetKeys") {
+		t.Errorf("Caller override should apply")
+	}
+	if !l.allow("web", "Read") {
+		t.Errorf("Methods without a limit should be free")
+	}
+
+	now = now.Add(time.Second)
+	if !l.allow("web", "GetKeys") || l.allow("web", "GetKeys") {
+		t.Errorf("Bucket should refill at the rate")
+	}
+
+	// Buckets that have refilled are dropped rather than kept for every caller ever seen
+	now = now.Add(bucketSweep)
+	if !l.allow("new", "GetKeys") || len(l.buckets) != 1 {
+		t.Errorf("Refilled buckets were kept: %v", l.buckets)
+	}
+
+	for _, bad := range []string{"GetKeys", "GetKeys=1", "GetKeys=0:1", "GetKeys=1:0"} {
+		if _, err := parseRateLimits(bad); err == nil {
+			t.Errorf("%q should not parse", bad)
+		}
+	}
+}
+
+func TestLoadShedding(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+	low := metadata.NewIncomingContext(context.Background(), metadata.Pairs(priorityMetadata, "low"))
+
+	if err := s.admit(low, "/pstore.PStoreService/Read", &pb.ReadRequest{}); err != nil {
+		t.Errorf("Idle server should not shed: %v", err)
+	}
+
+	for i := 0; i < *shedQueueDepth; i++ {
+		s.wq <- &WriteElement{}
+	}
+	if err := s.admit(low, "/pstore.PStoreService/Read", &pb.ReadRequest{}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Low priority work should be shed with a full queue: %v", err)
+	}
+	if err := s.admit(context.Background(), "/pstore.PStoreService/GetKeys", &pb.GetKeysRequest{AllKeys: true}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Full listings should be shed with a full queue: %v", err)
+	}
+	if err := s.admit(context.Background(), "/pstore.PStoreService/Scan", nil); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Scans should be shed with a full queue: %v", err)
+	}
+	if err := s.admit(context.Background(), "/pstore.PStoreService/Read", &pb.ReadRequest{}); err != nil {
+		t.Errorf("Normal work should not be shed: %v", err)
+	}
+
+	s.wq = make(chan *WriteElement, 100)
+	for i := 0; i < 100; i++ {
+		s.observeLatency(time.Second * 10)
+	}
+	if err := s.admit(low, "/pstore.PStoreService/Read", &pb.ReadRequest{}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Low priority work should be shed when backends are slow: %v", err)
+	}
+}
+
+func TestGatewayAdmission(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+	limits, err := parseRateLimits("Read=1:1")
+	if err != nil {
+		t.Fatalf("Bad limits: %v", err)
+	}
+	s.limiter = limits
+	mux := http.NewServeMux()
+	s.registerGateway(mux)
+
+	if rec := runHTTP(t, mux, "GET", "/v1/keys/things/one", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("First read should be let through: %v", rec.Code)
+	}
+	if rec := runHTTP(t, mux, "GET", "/v1/keys/things/one", "", nil); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Second read should be rate limited: %v", rec.Code)
+	}
+
+	for i := 0; i < *shedQueueDepth; i++ {
+		s.wq <- &WriteElement{}
+	}
+	if rec := runHTTP(t, mux, "GET", "/v1/keys", "", nil); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Full listing should be shed with a full queue: %v", rec.Code)
+	}
+	if rec := runHTTP(t, mux, "GET", "/v1/keys?prefix=things/", "", map[string]string{priorityMetadata: "low"}); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Low priority listing should be shed with a full queue: %v", rec.Code)
+	}
+	if rec := runHTTP(t, mux, "GET", "/v1/keys?prefix=things/", "", nil); rec.Code != http.StatusOK {
+		t.Errorf("Normal listing should not be shed: %v", rec.Code)
+	}
+}
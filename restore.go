@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+var (
+	snapshotDir      = flag.String("snapshot_dir", "", "Directory for periodic export archives, needed for Restore")
+	snapshotInterval = flag.Duration("snapshot_interval", time.Hour*24, "How often to take a snapshot")
+	snapshotKeep     = flag.Int("snapshot_keep", 7, "Number of snapshots to keep")
+)
+
+var (
+	snapshotCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_snapshots",
+	}, []string{"result"})
+	restoreCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_restores",
+	}, []string{"code"})
+)
+
+// snapshot describes an archive in the snapshot directory. Every change after
+// sequence may be missing from it, and nothing in it is newer than finished.
+type snapshot struct {
+	path     string
+	started  time.Time
+	finished time.Time
+	sequence int64
+}
+
+func snapshotName(started, finished time.Time, sequence int64) string {
+	return fmt.Sprintf("snapshot-%v-%v-%v.gz", started.UnixNano(), finished.UnixNano(), sequence)
+}
+
+// listSnapshots returns the snapshots in dir, oldest first
+func listSnapshots(dir string) ([]*snapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var result []*snapshot
+	for _, entry := range entries {
+		var started, finished, sequence int64
+		if _, err := fmt.Sscanf(entry.Name(), "snapshot-%d-%d-%d.gz", &started, &finished, &sequence); err != nil {
+			continue
+		}
+		result = append(result, &snapshot{
+			path:     filepath.Join(dir, entry.Name()),
+			started:  time.Unix(0, started),
+			finished: time.Unix(0, finished),
+			sequence: sequence,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].finished.Before(result[j].finished)
+	})
+	return result, nil
+}
+
+// lastSequence is the newest changelog sequence this process has handed out. Until it
+// hands one out that is the end of the last block leased, which covers every number an
+// earlier process could have handed out.
+func (s *Server) lastSequence(ctx context.Context) (int64, error) {
+	s.changeLock.Lock()
+	defer s.changeLock.Unlock()
+	if s.changeNext > 0 {
+		return s.changeNext - 1, nil
+	}
+
+	// The change lock stays held so no block is leased and used while the counter is read
+	defer s.lockKey(changelogSequence)()
+	state, _, err := s.loadCounter(ctx, changelogSequence, "")
+	if err != nil {
+		return 0, err
+	}
+	return state.GetValue(), nil
+}
+
+// takeSnapshot exports the whole store into dir and drops the oldest snapshots beyond keep
+func (s *Server) takeSnapshot(ctx context.Context, dir string, keep int) (string, error) {
+	started := time.Now()
+	sequence, err := s.lastSequence(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.CreateTemp(dir, "snapshot-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+
+	buffer := bufio.NewWriter(file)
+	err = s.exportTo(ctx, "", buffer)
+	if err == nil {
+		err = buffer.Flush()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, snapshotName(started, time.Now(), sequence))
+	if err := os.Rename(file.Name(), path); err != nil {
+		return "", err
+	}
+
+	snapshots, err := listSnapshots(dir)
+	if err != nil {
+		return path, err
+	}
+	for len(snapshots) > keep {
+		os.Remove(snapshots[0].path)
+		snapshots = snapshots[1:]
+	}
+	return path, nil
+}
+
+func (s *Server) runSnapshots(dir string) {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+		path, err := s.takeSnapshot(ctx, dir, *snapshotKeep)
+		cancel()
+		log.Printf("Took snapshot %v: %v", path, err)
+		snapshotCount.With(prometheus.Labels{"result": fmt.Sprintf("%v", status.Code(err))}).Inc()
+
+		time.Sleep(*snapshotInterval)
+	}
+}
+
+// restorable picks out the keys a restore touches, pstore's own keys such as
+// counters and sequences are never wound back but namespaced keys are
+func restorable(key, prefix string) bool {
+	if !strings.HasPrefix(key, prefix) || derived(key) {
+		return false
+	}
+	return !strings.HasPrefix(key, reservedPrefix) || strings.HasPrefix(key, namespacePrefix)
+}
+
+func (s *Server) Restore(ctx context.Context, req *pb.RestoreRequest) (*pb.RestoreResponse, error) {
+	resp, err := s.restore(ctx, req)
+	restoreCount.With(prometheus.Labels{"code": fmt.Sprintf("%v", status.Code(err))}).Inc()
+	return resp, err
+}
+
+// restore rebuilds the state at the requested time from the newest snapshot that finished
+// before it plus the changelog since, then writes and deletes keys to match that state
+func (s *Server) restore(ctx context.Context, req *pb.RestoreRequest) (*pb.RestoreResponse, error) {
+	if !s.changelog || s.snapshotDir == "" {
+		return nil, status.Errorf(codes.FailedPrecondition, "restores need both the changelog and snapshots")
+	}
+	target := time.Unix(0, req.GetTimestamp())
+	if req.GetTimestamp() <= 0 || target.After(time.Now()) {
+		return nil, status.Errorf(codes.InvalidArgument, "cannot restore to %v", target)
+	}
+
+	ns, err := s.namespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefix := namespacedKey(ns, req.GetPrefix())
+	if err := s.authorize(ctx, rightWrite, prefix); err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, rightDelete, prefix); err != nil {
+		return nil, err
+	}
+
+	snapshots, err := listSnapshots(s.snapshotDir)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to list snapshots: %v", err)
+	}
+	var snap *snapshot
+	for _, candidate := range snapshots {
+		if !candidate.finished.After(target) {
+			snap = candidate
+		}
+	}
+	if snap == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "no snapshot finished before %v", target)
+	}
+	if time.Since(snap.started) > *changelogRetention {
+		return nil, status.Errorf(codes.FailedPrecondition, "%v is older than the changelog retention", snap.path)
+	}
+	resp := &pb.RestoreResponse{Snapshot: filepath.Base(snap.path)}
+
+	file, err := os.Open(snap.path)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to open %v: %v", snap.path, err)
+	}
+	defer file.Close()
+	state := make(map[string]*anypb.Any)
+	err = readArchive(file, func(record *pb.BackupRecord) error {
+		if restorable(record.GetKey(), prefix) {
+			state[record.GetKey()] = record.GetValue()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Every event up to now has to be stored before it can be replayed, and none that
+	// falls between the snapshot and the target can be missing
+	if err := s.waitForChanges(ctx); err != nil {
+		return nil, err
+	}
+	gaps, err := s.changeGapsAfter(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, gap := range gaps {
+		if gap.GetTimestamp() <= target.UnixNano() && restorable(gap.GetKey(), prefix) &&
+			(gap.GetSequence() > snap.sequence || gap.GetTimestamp() >= snap.started.UnixNano()) {
+			return nil, status.Errorf(codes.DataLoss, "%v of %v at %v is missing from the changelog, unable to replay past it", gap.GetOp(), gap.GetKey(), gap.GetSequence())
+		}
+	}
+
+	keys, err := s.changeKeys(ctx, snap.sequence)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		event, err := s.readChange(ctx, key)
+		if status.Code(err) == codes.NotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if event.GetTimestamp() > target.UnixNano() {
+			break
+		}
+		if !restorable(event.GetKey(), prefix) {
+			continue
+		}
+		switch event.GetOp() {
+		case pb.ChangeEvent_WRITE:
+			state[event.GetKey()] = event.GetValue()
+		case pb.ChangeEvent_DELETE:
+			delete(state, event.GetKey())
+		}
+		resp.Replayed++
+	}
+
+	current, err := s.getKeys(ctx, &pb.GetKeysRequest{Prefix: prefix, AllKeys: prefix == ""})
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range current.GetKeys() {
+		if _, ok := state[key]; ok || !restorable(key, prefix) {
+			continue
+		}
+		if !req.GetDryRun() {
+			rec := s.newAudit(ctx, "delete", key, nil)
+			_, err := s.delete(ctx, keyNamespace(key), &pb.DeleteRequest{Key: key}, rec)
+			s.finishAudit(rec, err)
+			if err != nil && status.Code(err) != codes.NotFound {
+				return nil, err
+			}
+		}
+		resp.Deleted++
+	}
+
+	restored := make([]string, 0, len(state))
+	for key := range state {
+		restored = append(restored, key)
+	}
+	sort.Strings(restored)
+	for _, key := range restored {
+		now, err := s.read(ctx, &pb.ReadRequest{Key: key})
+		if err == nil && proto.Equal(now.GetValue(), state[key]) {
+			continue
+		}
+		if err != nil && status.Code(err) != codes.NotFound {
+			return nil, err
+		}
+		if !req.GetDryRun() {
+			rec := s.newAudit(ctx, "write", key, state[key].GetValue())
+			_, err := s.write(ctx, keyNamespace(key), &pb.WriteRequest{Key: key, Value: state[key]}, rec)
+			s.finishAudit(rec, err)
+			if err != nil {
+				return nil, err
+			}
+		}
+		resp.Written++
+	}
+
+	log.Printf("Restored %q to %v from %v: %v", prefix, target, snap.path, resp)
+	return resp, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func valueOf(s *Server, key string) string {
+	resp, err := s.Read(context.Background(), &pb.ReadRequest{Key: key})
+	if err != nil {
+		return status.Code(err).String()
+	}
+	return string(resp.GetValue().GetValue())
+}
+
+func TestRestore(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"), getTestBackend("secondary"))
+	ctx := context.Background()
+	if _, err := s.Restore(ctx, &pb.RestoreRequest{Timestamp: time.Now().UnixNano()}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Restore without snapshots should fail: %v", err)
+	}
+	s.changelog = true
+	s.snapshotDir = t.TempDir()
+
+	write := func(key, v string) {
+		if _, err := s.Write(ctx, &pb.WriteRequest{Key: key, Value: &anypb.Any{Value: []byte(v)}}); err != nil {
+			t.Fatalf("Bad write: %v", err)
+		}
+	}
+	write("things/a", "1")
+	write("things/b", "1")
+	write("other/a", "1")
+	if _, err := s.takeSnapshot(ctx, s.snapshotDir, 2); err != nil {
+		t.Fatalf("Bad snapshot: %v", err)
+	}
+	if _, err := s.Restore(ctx, &pb.RestoreRequest{Timestamp: time.Now().Add(-time.Hour).UnixNano()}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Restore before the first snapshot should fail: %v", err)
+	}
+
+	write("things/a", "2")
+	write("things/c", "1")
+	good := time.Now()
+
+	write("things/a", "bad")
+	s.Delete(ctx, &pb.DeleteRequest{Key: "things/b"})
+	write("things/d", "bad")
+	write("other/a", "2")
+
+	resp, err := s.Restore(ctx, &pb.RestoreRequest{Prefix: "things/", Timestamp: good.UnixNano(), DryRun: true})
+	if err != nil || resp.GetWritten() != 2 || resp.GetDeleted() != 1 || resp.GetReplayed() != 2 {
+		t.Fatalf("Bad dry run: %v, %v", resp, err)
+	}
+	if valueOf(s, "things/a") != "bad" {
+		t.Errorf("Dry run changed a value")
+	}
+
+	if _, err := s.Restore(ctx, &pb.RestoreRequest{Prefix: "things/", Timestamp: good.UnixNano()}); err != nil {
+		t.Fatalf("Bad restore: %v", err)
+	}
+	for key, want := range map[string]string{"things/a": "2", "things/b": "1", "things/c": "1", "things/d": "NotFound", "other/a": "2"} {
+		if got := valueOf(s, key); got != want {
+			t.Errorf("%v is %v after the restore, want %v", key, got, want)
+		}
+	}
+}
+
+func TestRestoreRefusesGaps(t *testing.T) {
+	primary := getTestBackend("primary")
+	s := getTestServer(primary)
+	s.changelog = true
+	s.snapshotDir = t.TempDir()
+	ctx := context.Background()
+
+	s.Write(ctx, &pb.WriteRequest{Key: "things/a", Value: &anypb.Any{Value: []byte("1")}})
+	if _, err := s.takeSnapshot(ctx, s.snapshotDir, 2); err != nil {
+		t.Fatalf("Bad snapshot: %v", err)
+	}
+	s.waitForChanges(ctx)
+
+	primary.down = true
+	s.recordChange(ctx, pb.ChangeEvent_WRITE, "things/a", &anypb.Any{Value: []byte("2")})
+	s.waitForChanges(ctx)
+	primary.down = false
+	s.Write(ctx, &pb.WriteRequest{Key: "other/a", Value: &anypb.Any{Value: []byte("1")}})
+
+	if _, err := s.Restore(ctx, &pb.RestoreRequest{Prefix: "things/", Timestamp: time.Now().UnixNano(), DryRun: true}); status.Code(err) != codes.DataLoss {
+		t.Errorf("Restore replayed across a gap: %v", err)
+	}
+	if _, err := s.Restore(ctx, &pb.RestoreRequest{Prefix: "other/", Timestamp: time.Now().UnixNano(), DryRun: true}); err != nil {
+		t.Errorf("Gap outside the prefix stopped the restore: %v", err)
+	}
+}
+
+func TestSnapshotSequenceAfterRestart(t *testing.T) {
+	primary := getTestBackend("primary")
+	s := getTestServer(primary)
+	s.changelog = true
+	ctx := context.Background()
+	s.Write(ctx, &pb.WriteRequest{Key: "things/a", Value: &anypb.Any{Value: []byte("1")}})
+	s.waitForChanges(ctx)
+	events, err := changes(s, &pb.ChangesRequest{})
+	if err != nil || len(events) != 1 {
+		t.Fatalf("Bad changes: %v, %v", events, err)
+	}
+
+	restarted := getTestServer(primary)
+	restarted.changelog = true
+	path, err := restarted.takeSnapshot(ctx, t.TempDir(), 2)
+	if err != nil {
+		t.Fatalf("Bad snapshot: %v", err)
+	}
+	snapshots, err := listSnapshots(filepath.Dir(path))
+	if err != nil || len(snapshots) != 1 || snapshots[0].sequence < events[0].GetSequence() {
+		t.Errorf("Snapshot after a restart claims to miss earlier changes: %v, %v", snapshots, err)
+	}
+}
+
+func TestSnapshotKeep(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+	dir := t.TempDir()
+	for i := 0; i < 3; i++ {
+		if _, err := s.takeSnapshot(context.Background(), dir, 2); err != nil {
+			t.Fatalf("Bad snapshot: %v", err)
+		}
+	}
+	snapshots, err := listSnapshots(dir)
+	if err != nil || len(snapshots) != 2 {
+		t.Errorf("Wrong snapshots kept: %v, %v", snapshots, err)
+	}
+}
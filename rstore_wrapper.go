@@ -2,13 +2,47 @@ package main
 
 import (
 	"context"
+	"fmt"
 
 	rstore_client "github.com/brotherlogic/rstore/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	pb "github.com/brotherlogic/pstore/proto"
 	rspb "github.com/brotherlogic/rstore/proto"
 )
 
+// getRStore dials rstore with the given credentials, rstore_client only dials in plain text
+func getRStore(creds credentials.TransportCredentials) (rstore_client.RStoreClient, error) {
+	conn, err := grpc.Dial("rstore.rstore:8080",
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(2000*1024*1024), grpc.MaxCallSendMsgSize(2000*1024*1024)))
+	if err != nil {
+		return nil, fmt.Errorf("dial error on %v -> %w", "rstore.rstore:8080", err)
+	}
+	return &rstoreConn{client: rspb.NewRStoreServiceClient(conn)}, nil
+}
+
+type rstoreConn struct {
+	client rspb.RStoreServiceClient
+}
+
+func (r *rstoreConn) Read(ctx context.Context, req *rspb.ReadRequest) (*rspb.ReadResponse, error) {
+	return r.client.Read(ctx, req)
+}
+func (r *rstoreConn) Write(ctx context.Context, req *rspb.WriteRequest) (*rspb.WriteResponse, error) {
+	return r.client.Write(ctx, req)
+}
+func (r *rstoreConn) GetKeys(ctx context.Context, req *rspb.GetKeysRequest) (*rspb.GetKeysResponse, error) {
+	return r.client.GetKeys(ctx, req)
+}
+func (r *rstoreConn) Delete(ctx context.Context, req *rspb.DeleteRequest) (*rspb.DeleteResponse, error) {
+	return r.client.Delete(ctx, req)
+}
+func (r *rstoreConn) Count(ctx context.Context, req *rspb.CountRequest) (*rspb.CountResponse, error) {
+	return r.client.Count(ctx, req)
+}
+
 type rstore_wrapper struct {
 	rc rstore_client.RStoreClient
 }
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"github.com/google/cel-go/cel"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+var (
+	scanCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_scan_values",
+	}, []string{"result"})
+)
+
+// filterError marks a filter which cannot be applied at all, as opposed to
+// one which failed on a single value
+type filterError struct {
+	err error
+}
+
+func (f *filterError) Error() string {
+	return f.err.Error()
+}
+
+// celFilter evaluates a CEL expression against decoded values. The expression is
+// type checked once for each message type it meets.
+type celFilter struct {
+	expr     string
+	registry *descriptorRegistry
+	programs map[string]cel.Program
+}
+
+func newCelFilter(expr string, registry *descriptorRegistry) (*celFilter, error) {
+	f := &celFilter{expr: expr, registry: registry, programs: make(map[string]cel.Program)}
+	if expr == "" {
+		return f, nil
+	}
+
+	// Catch syntax errors up front, before we know any types
+	env, err := cel.NewEnv()
+	if err != nil {
+		return nil, err
+	}
+	if _, iss := env.Parse(expr); iss.Err() != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "bad filter: %v", iss.Err())
+	}
+	return f, nil
+}
+
+func (f *celFilter) program(msg proto.Message) (cel.Program, error) {
+	name := string(msg.ProtoReflect().Descriptor().FullName())
+	if p, ok := f.programs[name]; ok {
+		return p, nil
+	}
+
+	env, err := cel.NewEnv(
+		cel.Types(msg),
+		cel.Variable("msg", cel.ObjectType(name)),
+		cel.Variable("key", cel.StringType),
+	)
+	if err != nil {
+		return nil, err
+	}
+	ast, iss := env.Compile(f.expr)
+	if iss.Err() != nil {
+		return nil, &filterError{fmt.Errorf("filter does not apply to %v: %w", name, iss.Err())}
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, &filterError{fmt.Errorf("filter must be a boolean, not %v", ast.OutputType())}
+	}
+	p, err := env.Program(ast)
+	if err != nil {
+		return nil, err
+	}
+
+	f.programs[name] = p
+	return p, nil
+}
+
+// matches decodes value and runs the filter over it, returning the decoded message
+func (f *celFilter) matches(key string, value *anypb.Any) (proto.Message, bool, error) {
+	msg, err := f.registry.unpack(value)
+	if err != nil {
+		if f.expr == "" {
+			return nil, true, nil
+		}
+		return nil, false, err
+	}
+	if f.expr == "" {
+		return msg, true, nil
+	}
+
+	p, err := f.program(msg)
+	if err != nil {
+		return msg, false, err
+	}
+	out, _, err := p.Eval(map[string]interface{}{"msg": msg, "key": key})
+	if err != nil {
+		return msg, false, err
+	}
+	match, ok := out.Value().(bool)
+	if !ok {
+		return msg, false, fmt.Errorf("filter returned %v", out.Value())
+	}
+	return msg, match, nil
+}
+
+// errStopScan ends a scan early without failing it
+var errStopScan = errors.New("stop scan")
+
+// scanValues reads every value under prefix and calls fn with those matching filter,
+// msg is nil for values whose type is not registered
+func (s *Server) scanValues(ctx context.Context, prefix, filterExpr string, fn func(key string, value *anypb.Any, msg proto.Message) error) error {
+	filter, err := newCelFilter(filterExpr, s.registry)
+	if err != nil {
+		return err
+	}
+
+	keys, err := s.GetKeys(ctx, &pb.GetKeysRequest{Prefix: prefix, AllKeys: prefix == ""})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys.GetKeys() {
+		if strings.HasPrefix(key, reservedPrefix) && !strings.HasPrefix(prefix, reservedPrefix) {
+			continue
+		}
+
+		resp, err := s.Read(ctx, &pb.ReadRequest{Key: key})
+		if err != nil {
+			// Keys the caller may list but not read are left out
+			if status.Code(err) == codes.NotFound || status.Code(err) == codes.PermissionDenied {
+				continue
+			}
+			return err
+		}
+
+		msg, match, err := filter.matches(key, resp.GetValue())
+		if err != nil {
+			ferr := &filterError{}
+			if errors.As(err, &ferr) {
+				return status.Errorf(codes.InvalidArgument, "%v", ferr)
+			}
+			scanCount.With(prometheus.Labels{"result": "error"}).Inc()
+			continue
+		}
+		if !match {
+			scanCount.With(prometheus.Labels{"result": "filtered"}).Inc()
+			continue
+		}
+
+		scanCount.With(prometheus.Labels{"result": "matched"}).Inc()
+		if err := fn(key, resp.GetValue(), msg); err != nil {
+			if err == errStopScan {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) Scan(req *pb.ScanRequest, stream grpc.ServerStreamingServer[pb.ScanResponse]) error {
+	sent := int32(0)
+	return s.scanValues(stream.Context(), req.GetPrefix(), req.GetFilter(), func(key string, value *anypb.Any, _ proto.Message) error {
+		if err := stream.Send(&pb.ScanResponse{Key: key, Value: value}); err != nil {
+			return err
+		}
+		sent++
+		if req.GetLimit() > 0 && sent >= req.GetLimit() {
+			return errStopScan
+		}
+		return nil
+	})
+}
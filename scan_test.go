@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+type testScanStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent []*pb.ScanResponse
+}
+
+func (t *testScanStream) Context() context.Context {
+	return t.ctx
+}
+
+func (t *testScanStream) Send(resp *pb.ScanResponse) error {
+	t.sent = append(t.sent, resp)
+	return nil
+}
+
+func scan(s *Server, req *pb.ScanRequest) (string, error) {
+	stream := &testScanStream{ctx: context.Background()}
+	err := s.Scan(req, stream)
+	var keys []string
+	for _, resp := range stream.sent {
+		keys = append(keys, resp.GetKey())
+	}
+	return strings.Join(keys, ","), err
+}
+
+func TestScan(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+	s.registerDescriptors(context.Background(), testDescriptors())
+
+	s.Write(context.Background(), &pb.WriteRequest{Key: "things/a", Value: thing("small", 2)})
+	s.Write(context.Background(), &pb.WriteRequest{Key: "things/b", Value: thing("large", 20)})
+	s.Write(context.Background(), &pb.WriteRequest{Key: "things/c", Value: thing("large", 30)})
+	s.Write(context.Background(), &pb.WriteRequest{Key: "things/raw", Value: &anypb.Any{Value: []byte{1}}})
+
+	got, err := scan(s, &pb.ScanRequest{Prefix: "things/", Filter: `msg.name == "large" && msg.size > 25`})
+	if err != nil || got != "things/c" {
+		t.Errorf("Bad filtered scan: %v, %v", got, err)
+	}
+
+	got, err = scan(s, &pb.ScanRequest{Prefix: "things/", Filter: `key.endsWith("b")`})
+	if err != nil || got != "things/b" {
+		t.Errorf("Bad key scan: %v, %v", got, err)
+	}
+
+	got, err = scan(s, &pb.ScanRequest{Prefix: "things/", Limit: 2})
+	if err != nil || strings.Count(got, ",") != 1 {
+		t.Errorf("Bad limited scan: %v, %v", got, err)
+	}
+
+	if _, err := scan(s, &pb.ScanRequest{Prefix: "things/", Filter: `msg.missing == 1`}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Filter on a missing field should fail: %v", err)
+	}
+	if _, err := scan(s, &pb.ScanRequest{Prefix: "things/", Filter: `msg.size +`}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Bad syntax should fail: %v", err)
+	}
+	if _, err := scan(s, &pb.ScanRequest{Prefix: "things/", Filter: `msg.size + 1`}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Non boolean filter should fail: %v", err)
+	}
+}
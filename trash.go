@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Deleted keys are held under this prefix, by their stored key, until they are purged
+const trashPrefix = reservedPrefix + "trash/"
+
+var (
+	trashRetention = flag.Duration("trash_retention", 0, "How long deleted keys are kept in the trash, zero deletes them at once")
+)
+
+var (
+	trashOps = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pstore_trash_ops",
+	}, []string{"op", "code"})
+)
+
+func trashKey(key string) string {
+	return trashPrefix + key
+}
+
+// trashable picks out the keys a delete moves to the trash, pstore's own keys are always removed at once
+func trashable(key string) bool {
+	return !strings.HasPrefix(key, reservedPrefix) || strings.HasPrefix(key, namespacePrefix)
+}
+
+// moveToTrash copies the value at key into the trash ahead of it being deleted,
+// a key that is not there is left for the delete to report
+func (s *Server) moveToTrash(ctx context.Context, key string) error {
+	resp, err := s.read(ctx, &pb.ReadRequest{Key: key})
+	if status.Code(err) == codes.NotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// The entry is stored in the clear, so the value is sealed as it would be under key
+	sealed, err := s.keyring.encrypt(key, resp.GetValue().GetValue())
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to encrypt %v: %v", trashKey(key), err)
+	}
+	entry := &pb.TrashEntry{
+		Key:       key,
+		Value:     &anypb.Any{TypeUrl: resp.GetValue().GetTypeUrl(), Value: sealed},
+		DeletedAt: time.Now().UnixNano(),
+		Caller:    callerFrom(ctx),
+	}
+	data, err := anypb.New(entry)
+	if err != nil {
+		return err
+	}
+	value, err := s.encodeValue(trashKey(key), data)
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to encode %v: %v", trashKey(key), err)
+	}
+	_, err = s.writeAll(ctx, &pb.WriteRequest{Key: trashKey(key), Value: &anypb.Any{TypeUrl: data.GetTypeUrl(), Value: value}}, nil)
+	trashOps.With(prometheus.Labels{"op": "trash", "code": fmt.Sprintf("%v", status.Code(err))}).Inc()
+	return err
+}
+
+// loadTrash reads the trash entry for key straight from the primary, leaving its value sealed
+func (s *Server) loadTrash(ctx context.Context, key string) (*pb.TrashEntry, error) {
+	resp, err := s.runRead(ctx, s.clients[0], &pb.ReadRequest{Key: trashKey(key)})
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.decodeValue(trashKey(key), resp.GetValue())
+	if err != nil {
+		return nil, status.Errorf(codes.DataLoss, "bad trash entry for %v: %v", key, err)
+	}
+	entry := &pb.TrashEntry{}
+	if err := proto.Unmarshal(data.GetValue(), entry); err != nil {
+		return nil, status.Errorf(codes.DataLoss, "bad trash entry for %v: %v", key, err)
+	}
+	return entry, nil
+}
+
+// readTrash reads the trash entry for key and opens its value
+func (s *Server) readTrash(ctx context.Context, key string) (*pb.TrashEntry, error) {
+	entry, err := s.loadTrash(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if entry.GetValue() != nil {
+		value, err := s.keyring.decrypt(key, entry.GetValue().GetValue())
+		if err != nil {
+			return nil, status.Errorf(codes.DataLoss, "unable to decrypt trash entry for %v: %v", key, err)
+		}
+		entry.Value.Value = value
+	}
+	return entry, nil
+}
+
+func (s *Server) ListTrash(ctx context.Context, req *pb.ListTrashRequest) (*pb.ListTrashResponse, error) {
+	ns, err := s.namespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefix := namespacedKey(ns, req.GetPrefix())
+	visible, err := s.authorizeList(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := s.getKeys(ctx, &pb.GetKeysRequest{Prefix: trashKey(prefix)})
+	if err != nil {
+		return nil, err
+	}
+	var original []string
+	for _, key := range keys.GetKeys() {
+		original = append(original, strings.TrimPrefix(key, trashPrefix))
+	}
+
+	resp := &pb.ListTrashResponse{}
+	for _, key := range visible(original) {
+		entry, err := s.loadTrash(ctx, key)
+		if status.Code(err) == codes.NotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entry.Value = nil
+		entry.Key = strings.TrimPrefix(entry.GetKey(), namespacedKey(ns, ""))
+		resp.Entries = append(resp.Entries, entry)
+	}
+	sort.SliceStable(resp.Entries, func(i, j int) bool {
+		return resp.Entries[i].GetDeletedAt() < resp.Entries[j].GetDeletedAt()
+	})
+	return resp, nil
+}
+
+func (s *Server) Undelete(ctx context.Context, req *pb.UndeleteRequest) (*pb.UndeleteResponse, error) {
+	ns, err := s.namespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key := namespacedKey(ns, req.GetKey())
+	rec := s.newAudit(ctx, "undelete", key, nil)
+	err = s.authorize(ctx, rightWrite, key)
+	if err == nil {
+		err = s.undelete(ctx, ns, key, rec)
+	}
+	s.finishAudit(rec, err)
+	trashOps.With(prometheus.Labels{"op": "undelete", "code": fmt.Sprintf("%v", status.Code(err))}).Inc()
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UndeleteResponse{}, nil
+}
+
+// undelete writes the trashed value back to key, refusing to replace a value written since the delete.
+// The key lock is held throughout as every delete fills the trash under it.
+func (s *Server) undelete(ctx context.Context, ns, key string, rec *auditRecord) error {
+	indexes := s.indexesFor(key)
+	unlockIndexes, err := lockIndexes(ctx, indexes)
+	if err != nil {
+		return err
+	}
+	defer unlockIndexes()
+	defer s.lockKey(key)()
+
+	entry, err := s.readTrash(ctx, key)
+	if status.Code(err) == codes.NotFound {
+		return status.Errorf(codes.NotFound, "%v is not in the trash", key)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = s.read(ctx, &pb.ReadRequest{Key: key})
+	if err == nil {
+		return status.Errorf(codes.AlreadyExists, "%v has been written since it was deleted", key)
+	}
+	if status.Code(err) != codes.NotFound {
+		return err
+	}
+
+	req := &pb.WriteRequest{Key: key, Value: entry.GetValue()}
+	value, err := s.encodeWrite(req)
+	if err != nil {
+		return err
+	}
+	if _, err := s.writeLocked(ctx, ns, req, value, indexes, rec); err != nil {
+		return err
+	}
+	if _, err := s.deleteAll(ctx, &pb.DeleteRequest{Key: trashKey(key)}, nil); err != nil {
+		log.Printf("Undeleted %v but could not empty it from the trash: %v", key, err)
+	}
+	return nil
+}
+
+// purgeTrash removes everything deleted before the cutoff
+func (s *Server) purgeTrash(ctx context.Context, cutoff time.Time) (int, error) {
+	keys, err := s.runGetKeys(ctx, s.clients[0], &pb.GetKeysRequest{Prefix: trashPrefix})
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, key := range keys.GetKeys() {
+		entry, err := s.loadTrash(ctx, strings.TrimPrefix(key, trashPrefix))
+		if status.Code(err) == codes.NotFound {
+			continue
+		}
+		if status.Code(err) == codes.DataLoss {
+			// One bad entry should not keep the rest of the trash around
+			log.Printf("Unable to purge %v: %v", key, err)
+			trashOps.With(prometheus.Labels{"op": "purge", "code": codes.DataLoss.String()}).Inc()
+			continue
+		}
+		if err != nil {
+			return count, err
+		}
+		if entry.GetDeletedAt() >= cutoff.UnixNano() {
+			continue
+		}
+		_, err = s.deleteAll(ctx, &pb.DeleteRequest{Key: key}, nil)
+		trashOps.With(prometheus.Labels{"op": "purge", "code": fmt.Sprintf("%v", status.Code(err))}).Inc()
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+func (s *Server) runTrashPurge() {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+		count, err := s.purgeTrash(ctx, time.Now().Add(-s.trashRetention))
+		cancel()
+		log.Printf("Purged %v keys from the trash: %v", count, err)
+
+		time.Sleep(time.Hour)
+	}
+}
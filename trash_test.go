@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestTrash(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"), getTestBackend("secondary"))
+	s.trashRetention = time.Hour
+	ctx := context.Background()
+
+	s.Write(ctx, &pb.WriteRequest{Key: "things/one", Value: &anypb.Any{Value: []byte("hello")}})
+	s.Write(ctx, &pb.WriteRequest{Key: "things/two", Value: &anypb.Any{Value: []byte("there")}})
+	if _, err := s.Delete(ctx, &pb.DeleteRequest{Key: "things/one"}); err != nil {
+		t.Fatalf("Bad delete: %v", err)
+	}
+	if _, err := s.Delete(ctx, &pb.DeleteRequest{Key: "things/two", Hard: true}); err != nil {
+		t.Fatalf("Bad hard delete: %v", err)
+	}
+	if valueOf(s, "things/one") != "NotFound" {
+		t.Errorf("Deleted key can still be read")
+	}
+
+	trash, err := s.ListTrash(ctx, &pb.ListTrashRequest{Prefix: "things/"})
+	if err != nil || len(trash.GetEntries()) != 1 || trash.GetEntries()[0].GetKey() != "things/one" || trash.GetEntries()[0].GetValue() != nil {
+		t.Fatalf("Bad trash: %v, %v", trash, err)
+	}
+
+	if _, err := s.Undelete(ctx, &pb.UndeleteRequest{Key: "things/two"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Hard deleted key was undeleted: %v", err)
+	}
+	if _, err := s.Undelete(ctx, &pb.UndeleteRequest{Key: "things/one"}); err != nil {
+		t.Fatalf("Bad undelete: %v", err)
+	}
+	if valueOf(s, "things/one") != "hello" {
+		t.Errorf("Undelete did not bring the value back: %v", valueOf(s, "things/one"))
+	}
+
+	s.Delete(ctx, &pb.DeleteRequest{Key: "things/one"})
+	s.Write(ctx, &pb.WriteRequest{Key: "things/one", Value: &anypb.Any{Value: []byte("newer")}})
+	if _, err := s.Undelete(ctx, &pb.UndeleteRequest{Key: "things/one"}); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Undelete replaced a newer value: %v", err)
+	}
+
+	if count, err := s.purgeTrash(ctx, time.Now().Add(-time.Hour)); err != nil || count != 0 {
+		t.Errorf("Purged entries inside the retention: %v, %v", count, err)
+	}
+	if count, err := s.purgeTrash(ctx, time.Now()); err != nil || count != 1 {
+		t.Errorf("Bad purge: %v, %v", count, err)
+	}
+	trash, err = s.ListTrash(ctx, &pb.ListTrashRequest{})
+	if err != nil || len(trash.GetEntries()) != 0 {
+		t.Errorf("Trash was not emptied: %v, %v", trash, err)
+	}
+}
+
+func TestTrashIsEncrypted(t *testing.T) {
+	primary := getTestBackend("primary")
+	s := getTestServer(primary)
+	s.trashRetention = time.Hour
+	s.keyring = testKeyring(t, "old")
+	ctx := context.Background()
+
+	s.Write(ctx, &pb.WriteRequest{Key: "things/one", Value: &anypb.Any{Value: []byte("secret")}})
+	if _, err := s.Delete(ctx, &pb.DeleteRequest{Key: "things/one"}); err != nil {
+		t.Fatalf("Bad delete: %v", err)
+	}
+	raw, err := primary.Read(ctx, &pb.ReadRequest{Key: trashKey("things/one")})
+	if err != nil || bytes.Contains(raw.GetValue().GetValue(), []byte("secret")) {
+		t.Fatalf("Trash entry holds the value in the clear: %v, %v", raw, err)
+	}
+
+	if _, err := s.Undelete(ctx, &pb.UndeleteRequest{Key: "things/one"}); err != nil {
+		t.Fatalf("Bad undelete: %v", err)
+	}
+	if valueOf(s, "things/one") != "secret" {
+		t.Errorf("Undelete did not bring the value back: %v", valueOf(s, "things/one"))
+	}
+}
+
+func TestUndeleteHoldsKeyLock(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+	s.trashRetention = time.Hour
+	ctx := context.Background()
+
+	s.Write(ctx, &pb.WriteRequest{Key: "things/one", Value: &anypb.Any{Value: []byte("hello")}})
+	s.Delete(ctx, &pb.DeleteRequest{Key: "things/one"})
+
+	unlock := s.lockKey("things/one")
+	done := make(chan error)
+	go func() {
+		_, err := s.Undelete(ctx, &pb.UndeleteRequest{Key: "things/one"})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("Undelete ran while the key was locked: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	if err := <-done; err != nil {
+		t.Errorf("Bad undelete: %v", err)
+	}
+}
+
+func TestPurgeSkipsBadEntries(t *testing.T) {
+	primary := getTestBackend("primary")
+	s := getTestServer(primary)
+	s.trashRetention = time.Hour
+	s.keyring = testKeyring(t, "old")
+	ctx := context.Background()
+
+	s.Write(ctx, &pb.WriteRequest{Key: "things/one", Value: &anypb.Any{Value: []byte("hello")}})
+	s.Delete(ctx, &pb.DeleteRequest{Key: "things/one"})
+	primary.Write(ctx, &pb.WriteRequest{Key: trashKey("things/bad"), Value: &anypb.Any{Value: []byte("garbage")}})
+
+	// Purging never needs the key the value was sealed with
+	s.keyring = nil
+	if count, err := s.purgeTrash(ctx, time.Now()); err != nil || count != 1 {
+		t.Errorf("Bad purge: %v, %v", count, err)
+	}
+}
+
+func TestTrashIsReencrypted(t *testing.T) {
+	primary := getTestBackend("primary")
+	s := getTestServer(primary)
+	s.trashRetention = time.Hour
+	s.changelog = true
+	s.keyring = testKeyring(t, "old")
+	ctx := context.Background()
+
+	s.Write(ctx, &pb.WriteRequest{Key: "things/one", Value: &anypb.Any{Value: []byte("secret")}})
+	s.Delete(ctx, &pb.DeleteRequest{Key: "things/one"})
+	s.waitForChanges(ctx)
+
+	s.keyring = testKeyring(t, "new")
+	if _, err := s.reencrypt(ctx); err != nil {
+		t.Fatalf("Bad re-encryption: %v", err)
+	}
+
+	entry, err := s.loadTrash(ctx, "things/one")
+	if err != nil {
+		t.Fatalf("Bad trash entry: %v", err)
+	}
+	if ev, encrypted, err := parseEncrypted(entry.GetValue().GetValue()); !encrypted || err != nil || ev.GetKeyId() != "new" {
+		t.Errorf("Trashed value was not rotated: %v, %v", ev, err)
+	}
+
+	keys, err := s.changeKeys(ctx, 0)
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("Bad changelog: %v, %v", keys, err)
+	}
+	raw, err := primary.Read(ctx, &pb.ReadRequest{Key: keys[0]})
+	if err != nil {
+		t.Fatalf("Bad changelog read: %v", err)
+	}
+	data, _ := s.decodeValue(keys[0], raw.GetValue())
+	event := &pb.ChangeEvent{}
+	proto.Unmarshal(data.GetValue(), event)
+	if ev, encrypted, err := parseEncrypted(event.GetValue().GetValue()); !encrypted || err != nil || ev.GetKeyId() != "new" {
+		t.Errorf("Changelog value was not rotated: %v, %v", ev, err)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strings"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Keys under this prefix belong to pstore itself rather than to callers
+const reservedPrefix = "_pstore/"
+
+// checkCallerKey rejects keys under reservedPrefix, which callers can only reach
+// through the RPCs that own them
+func checkCallerKey(key string) error {
+	if strings.HasPrefix(key, reservedPrefix) {
+		return status.Errorf(codes.InvalidArgument, "keys under %v are reserved", reservedPrefix)
+	}
+	return nil
+}
+
+// dropReserved removes pstore's own keys from a listing
+func dropReserved(keys []string) []string {
+	var result []string
+	for _, key := range keys {
+		if !strings.HasPrefix(key, reservedPrefix) {
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+var (
+	typeBindings = flag.String("type_bindings", "", "Prefixes which only accept one message type, e.g. users/=type.googleapis.com/users.User")
+)
+
+// Typed values start with this header followed by a serialized StoredValue
+var typeHeader = []byte{0x00, 'P', 'T'}
+
+// parseTypeBindings reads a list of prefix=type_url pairs
+func parseTypeBindings(bindings string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, entry := range strings.Split(bindings, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("bad type binding %q", entry)
+		}
+		result[parts[0]] = parts[1]
+	}
+	return result, nil
+}
+
+// checkType rejects values whose type does not match the binding for the
+// longest prefix of key, keys with no binding accept anything
+func (s *Server) checkType(key string, value *anypb.Any) error {
+	best := -1
+	bound := ""
+	for prefix, typeUrl := range s.typeBindings {
+		if strings.HasPrefix(key, prefix) && len(prefix) > best {
+			best = len(prefix)
+			bound = typeUrl
+		}
+	}
+
+	if best >= 0 && value.GetTypeUrl() != bound {
+		return status.Errorf(codes.InvalidArgument, "%v only accepts %v, not %q", key, bound, value.GetTypeUrl())
+	}
+	return nil
+}
+
+func wrapType(typeUrl string, value []byte) ([]byte, error) {
+	data, err := proto.Marshal(&pb.StoredValue{TypeUrl: typeUrl, Value: value})
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, typeHeader...), data...), nil
+}
+
+// unwrapType splits a stored value into its type URL and body, values
+// written before types were stored come back with an empty type URL
+func unwrapType(value []byte) (string, []byte, error) {
+	if !bytes.HasPrefix(value, typeHeader) {
+		return "", value, nil
+	}
+	sv := &pb.StoredValue{}
+	if err := proto.Unmarshal(value[len(typeHeader):], sv); err != nil {
+		return "", nil, fmt.Errorf("bad stored value: %w", err)
+	}
+	return sv.GetTypeUrl(), sv.GetValue(), nil
+}
+
+// encodeValue turns a value from a caller into the bytes handed to the backends
+func (s *Server) encodeValue(key string, value *anypb.Any) ([]byte, error) {
+	data, err := s.compression.compress(key, value.GetValue())
+	if err != nil {
+		return nil, err
+	}
+	data, err = s.keyring.encrypt(key, data)
+	if err != nil {
+		return nil, err
+	}
+	data, err = wrapType(value.GetTypeUrl(), data)
+	if err != nil {
+		return nil, err
+	}
+	return addChecksum(key, data), nil
+}
+
+// decodeValue undoes encodeValue for a value read from a backend
+func (s *Server) decodeValue(key string, value *anypb.Any) (*anypb.Any, error) {
+	data, err := verifyChecksum(key, value.GetValue())
+	if err != nil {
+		return nil, err
+	}
+	typeUrl, data, err := unwrapType(data)
+	if err != nil {
+		return nil, err
+	}
+	if typeUrl == "" {
+		typeUrl = value.GetTypeUrl()
+	}
+	data, err = s.keyring.decrypt(key, data)
+	if err != nil {
+		return nil, err
+	}
+	data, err = decompress(data)
+	if err != nil {
+		return nil, err
+	}
+	return &anypb.Any{TypeUrl: typeUrl, Value: data}, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestTypeURLSurvivesRepair(t *testing.T) {
+	primary := getTestBackend("primary")
+	redis := getTestBackend("redis")
+	redis.dropTypes = true
+	s := getTestServer(primary, redis)
+
+	value, _ := anypb.New(&pb.ReadRequest{Key: "inner"})
+	s.Write(context.Background(), &pb.WriteRequest{Key: "key", Value: value})
+
+	// Lose the key from the primary, then have it repaired from redis
+	primary.Delete(context.Background(), &pb.DeleteRequest{Key: "key"})
+	s.clients = []pstore{redis, primary}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := s.Read(ctx, &pb.ReadRequest{Key: "key"})
+	if err != nil || resp.GetValue().GetTypeUrl() != value.GetTypeUrl() {
+		t.Fatalf("Type URL was lost reading from redis: %v, %v", resp, err)
+	}
+
+	for len(s.wq) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	s.runElem(<-s.wq)
+
+	s.clients = []pstore{primary, redis}
+	resp, err = s.Read(ctx, &pb.ReadRequest{Key: "key"})
+	if err != nil || resp.GetValue().GetTypeUrl() != value.GetTypeUrl() {
+		t.Errorf("Type URL was lost in repair: %v, %v", resp, err)
+	}
+}
+
+func TestTypeBindings(t *testing.T) {
+	s := getTestServer(getTestBackend("primary"))
+	bindings, err := parseTypeBindings("requests/=type.googleapis.com/pstore.ReadRequest")
+	if err != nil {
+		t.Fatalf("Bad bindings: %v", err)
+	}
+	s.typeBindings = bindings
+
+	good, _ := anypb.New(&pb.ReadRequest{})
+	bad, _ := anypb.New(&pb.WriteRequest{})
+
+	if _, err := s.Write(context.Background(), &pb.WriteRequest{Key: "requests/one", Value: good}); err != nil {
+		t.Errorf("Bound type should be accepted: %v", err)
+	}
+	if _, err := s.Write(context.Background(), &pb.WriteRequest{Key: "requests/two", Value: bad}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Wrong type should be rejected: %v", err)
+	}
+	if _, err := s.Write(context.Background(), &pb.WriteRequest{Key: "other/two", Value: bad}); err != nil {
+		t.Errorf("Unbound prefixes accept anything: %v", err)
+	}
+}
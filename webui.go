@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/brotherlogic/pstore/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var uiTemplates = template.Must(template.New("browse").Parse(`<html>
+<head><title>pstore: {{.Prefix}}</title></head>
+<body>
+<h2>{{range .Crumbs}}<a href="/ui/?prefix={{.Prefix}}">{{.Name}}</a> {{end}}</h2>
+<ul>
+{{range .Dirs}}<li><a href="/ui/?prefix={{$.Prefix}}{{.}}">{{.}}</a></li>
+{{end}}{{range .Keys}}<li><a href="/ui/key?key={{$.Prefix}}{{.}}">{{.}}</a></li>
+{{end}}</ul>
+</body>
+</html>`))
+
+var _ = template.Must(uiTemplates.New("key").Parse(`<html>
+<head><title>pstore: {{.Key}}</title></head>
+<body>
+<h2>{{.Key}}</h2>
+{{if .Error}}<p>Read failed: {{.Error}}</p>{{end}}
+<p>Type: {{.TypeUrl}}</p>
+<pre>{{.Value}}</pre>
+<h3>Backends</h3>
+<table border="1">
+<tr><th>Backend</th><th>Present</th><th>Timestamp</th><th>Size</th><th>Checksum</th></tr>
+{{range .Backends}}<tr><td>{{.Name}}</td><td>{{.Present}}</td><td>{{.Timestamp}}</td><td>{{.Size}}</td><td>{{.Checksum}}</td></tr>
+{{end}}</table>
+<form method="POST" action="/ui/repair?key={{.Key}}"><input type="hidden" name="token" value="{{.Token}}"><input type="submit" value="Repair"></form>
+{{if .Repaired}}<p>Queued {{.Repaired}} repairs</p>{{end}}
+</body>
+</html>`))
+
+type uiCrumb struct {
+	Name   string
+	Prefix string
+}
+
+type uiBackend struct {
+	Name      string
+	Present   string
+	Timestamp string
+	Size      int
+	Checksum  string
+}
+
+// registerUI adds the key browser to mux
+func (s *Server) registerUI(mux *http.ServeMux) {
+	s.uiSecret = make([]byte, 32)
+	if _, err := rand.Read(s.uiSecret); err != nil {
+		log.Fatalf("Unable to generate UI secret: %v", err)
+	}
+
+	mux.HandleFunc("GET /ui/{$}", s.httpAuth("GetKeys", s.uiBrowse))
+	mux.HandleFunc("GET /ui/key", s.httpAuth("Read", s.uiKey))
+	mux.HandleFunc("POST /ui/repair", s.httpAuth("Write", s.uiRepair))
+}
+
+// uiToken ties a repair form to the caller and key it was rendered for, so another
+// site can't post one on the caller's behalf
+func (s *Server) uiToken(ctx context.Context, key string) string {
+	mac := hmac.New(sha256.New, s.uiSecret)
+	mac.Write([]byte(callerFrom(ctx) + "\x00" + key))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// uiKeyFor maps a key in the caller's view to where it is stored, checking the caller may use right on it
+func (s *Server) uiKeyFor(ctx context.Context, right, key string) (string, error) {
+	if err := checkCallerKey(key); err != nil {
+		return "", err
+	}
+	ns, err := s.namespace(ctx)
+	if err != nil {
+		return "", err
+	}
+	stored := namespacedKey(ns, key)
+	return stored, s.authorize(ctx, right, stored)
+}
+
+// splitKeys groups keys under prefix into the next level of the tree
+func splitKeys(prefix string, keys []string) ([]string, []string) {
+	dirs := make(map[string]bool)
+	var leaves []string
+	for _, key := range keys {
+		rest := strings.TrimPrefix(key, prefix)
+		if i := strings.Index(rest, "/"); i >= 0 {
+			dirs[rest[:i+1]] = true
+		} else {
+			leaves = append(leaves, rest)
+		}
+	}
+
+	var dirList []string
+	for dir := range dirs {
+		dirList = append(dirList, dir)
+	}
+	sort.Strings(dirList)
+	sort.Strings(leaves)
+	return dirList, leaves
+}
+
+func crumbs(prefix string) []uiCrumb {
+	result := []uiCrumb{{Name: "/", Prefix: ""}}
+	parts := strings.SplitAfter(prefix, "/")
+	built := ""
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		built += part
+		result = append(result, uiCrumb{Name: part, Prefix: built})
+	}
+	return result
+}
+
+func (s *Server) uiBrowse(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := httpContext(r)
+	defer cancel()
+
+	prefix := r.URL.Query().Get("prefix")
+	resp, err := s.GetKeys(ctx, &pb.GetKeysRequest{Prefix: prefix, AllKeys: prefix == ""})
+	if err != nil {
+		writeHTTPError(w, r, err)
+		return
+	}
+
+	dirs, keys := splitKeys(prefix, resp.GetKeys())
+	err = uiTemplates.ExecuteTemplate(w, "browse", map[string]interface{}{
+		"Prefix": prefix,
+		"Crumbs": crumbs(prefix),
+		"Dirs":   dirs,
+		"Keys":   keys,
+	})
+	if err != nil {
+		log.Printf("Unable to render browse page: %v", err)
+	}
+}
+
+// backendState reads key directly from every backend
+func (s *Server) backendState(ctx context.Context, key string) []uiBackend {
+	var result []uiBackend
+	for _, c := range s.clients {
+		state := uiBackend{Name: c.Name()}
+		resp, err := s.runRead(ctx, c, &pb.ReadRequest{Key: key})
+		switch {
+		case err == nil:
+			state.Present = "yes"
+			state.Timestamp = time.Unix(resp.GetTimestamp(), 0).String()
+			state.Size = len(resp.GetValue().GetValue())
+			state.Checksum = "ok"
+			if _, cerr := verifyChecksum(key, resp.GetValue().GetValue()); cerr != nil {
+				state.Checksum = "BAD"
+			} else if !bytes.HasPrefix(resp.GetValue().GetValue(), checksumHeader) {
+				state.Checksum = "none"
+			}
+		case status.Code(err) == codes.NotFound:
+			state.Present = "no"
+		default:
+			state.Present = "error: " + err.Error()
+		}
+		result = append(result, state)
+	}
+	return result
+}
+
+func (s *Server) renderUIKey(w http.ResponseWriter, r *http.Request, key string, repaired int) {
+	ctx, cancel := httpContext(r)
+	defer cancel()
+
+	stored, err := s.uiKeyFor(ctx, rightRead, key)
+	if err != nil {
+		writeHTTPError(w, r, err)
+		return
+	}
+	page := map[string]interface{}{
+		"Key":      key,
+		"Backends": s.backendState(ctx, stored),
+		"Repaired": repaired,
+		"Token":    s.uiToken(ctx, key),
+	}
+	resp, err := s.Read(ctx, &pb.ReadRequest{Key: key})
+	if err != nil {
+		page["Error"] = err.Error()
+	} else {
+		rendered := s.renderKey(key, resp)
+		page["TypeUrl"] = rendered.TypeUrl
+		if len(rendered.Value) > 0 {
+			indented := &bytes.Buffer{}
+			if jerr := json.Indent(indented, rendered.Value, "", "  "); jerr == nil {
+				page["Value"] = indented.String()
+			} else {
+				page["Value"] = string(rendered.Value)
+			}
+		} else {
+			page["Value"] = rendered.Base64
+		}
+	}
+
+	if err := uiTemplates.ExecuteTemplate(w, "key", page); err != nil {
+		log.Printf("Unable to render key page: %v", err)
+	}
+}
+
+func (s *Server) uiKey(w http.ResponseWriter, r *http.Request) {
+	s.renderUIKey(w, r, r.URL.Query().Get("key"), 0)
+}
+
+func (s *Server) uiRepair(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := httpContext(r)
+	defer cancel()
+
+	key := r.URL.Query().Get("key")
+	if !hmac.Equal([]byte(r.PostFormValue("token")), []byte(s.uiToken(ctx, key))) {
+		writeHTTPError(w, r, status.Errorf(codes.PermissionDenied, "bad or missing form token"))
+		return
+	}
+	stored, err := s.uiKeyFor(ctx, rightWrite, key)
+	if err != nil {
+		writeHTTPError(w, r, err)
+		return
+	}
+	count, err := s.repairKey(ctx, stored)
+	if err != nil {
+		writeHTTPError(w, r, err)
+		return
+	}
+	s.renderUIKey(w, r, key, count)
+}
+
+// repairKey makes every backend agree with the primary, as read repair does: a missing
+// key is deleted elsewhere, and a corrupt primary is itself repaired from a healthy
+// replica. Returns the number of repairs queued.
+func (s *Server) repairKey(ctx context.Context, key string) (int, error) {
+	req := &pb.ReadRequest{Key: key}
+	good, err := s.runRead(ctx, s.clients[0], req)
+	if err == nil {
+		if _, cerr := verifyChecksum(key, good.GetValue().GetValue()); cerr != nil {
+			good, err = s.healthyRead(ctx, req, cerr)
+			if err != nil {
+				return 0, err
+			}
+			// healthyRead has queued the primary
+			return 1 + s.queueRepairs(ctx, key, good, s.clients[1:]), nil
+		}
+	} else if status.Code(err) != codes.NotFound {
+		return 0, err
+	}
+
+	return s.queueRepairs(ctx, key, good, s.clients[1:]), nil
+}
+
+// queueRepairs queues good, or a delete if it is nil, for each client holding something else
+func (s *Server) queueRepairs(ctx context.Context, key string, good *pb.ReadResponse, clients []pstore) int {
+	count := 0
+	for _, c := range clients {
+		resp, err := s.runRead(ctx, c, &pb.ReadRequest{Key: key})
+		if err != nil && status.Code(err) != codes.NotFound {
+			continue
+		}
+		if good == nil {
+			if err != nil {
+				continue
+			}
+			s.wq <- &WriteElement{key: key, cname: c.Name(), delete: true}
+		} else {
+			if err == nil && bytes.Equal(resp.GetValue().GetValue(), good.GetValue().GetValue()) {
+				continue
+			}
+			s.wq <- &WriteElement{key: key, value: good.GetValue(), cname: c.Name()}
+		}
+		count++
+	}
+	return count
+}
@@ -11,9 +11,10 @@ import (
 )
 
 type WriteElement struct {
-	key   string
-	value []byte
-	cname string
+	key    string
+	value  *anypb.Any
+	cname  string
+	delete bool
 }
 
 func (s *Server) runWriteQueue() {
@@ -29,10 +30,23 @@ func (s *Server) runElem(we *WriteElement) {
 
 	for _, c := range s.clients {
 		if c.Name() == we.cname {
-			_, err := c.Write(ctx, &pb.WriteRequest{
-				Key:   we.key,
-				Value: &anypb.Any{Value: we.value},
-			})
+			// Repairs for a backend that is down wait for its circuit to close
+			err := s.checkBackend(c)
+			if err == nil {
+				t := time.Now()
+				if we.delete {
+					_, err = c.Delete(ctx, &pb.DeleteRequest{Key: we.key})
+				} else {
+					_, err = c.Write(ctx, &pb.WriteRequest{
+						Key:   we.key,
+						Value: we.value,
+					})
+				}
+				s.recordBackend(ctx, c, time.Since(t), err)
+			}
+			if err != nil && s.breakers.isOpen(c.Name()) {
+				s.breakers.hold(c.Name(), we)
+			}
 			log.Printf("Side Write (%v, %v) -> %v", we.cname, we.key, err)
 		}
 	}